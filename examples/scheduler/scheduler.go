@@ -14,7 +14,6 @@ import (
 	"github.com/ondrej-smola/mesos-go-http/lib/client/leader"
 	"github.com/ondrej-smola/mesos-go-http/examples/scheduler/metrics"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
-	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
 	"github.com/ondrej-smola/mesos-go-http/lib/resources/find"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/ack"
@@ -104,6 +103,11 @@ func run(cfg *config) {
 
 		a.log.Log("event", "failed", "attempt", attempt, "err", err)
 		fl.Close()
+
+		if !scheduler.IsRecoverable(err) {
+			a.log.Log("event", "framework_removed", "err", err)
+			return
+		}
 	}
 }
 func serveMetrics(metrics *metrics.PrometheusMetrics, endpoint string, log log.Logger) {
@@ -146,16 +150,13 @@ func (a *app) handleOffers(offers []*mesos.Offer, flow flow.Flow) error {
 		logger.Log("resources", resources)
 		tasks := []*mesos.TaskInfo{}
 
-		availableResources := filter.All(filter.Unreserved(), resources...)
+		availableResources := resources
+		request := find.Request{Cpus: a.cfg.taskCpus, Mem: a.cfg.taskMem, Policy: find.UNRESERVED_ONLY}
 
 		for a.tasksLaunched < a.cfg.numTasks {
 			logger.Log("resources", availableResources)
 
-			cpus, remaining, ok := find.Scalar(mesos.CPUS, a.cfg.taskCpus, availableResources...)
-			if !ok {
-				break
-			}
-			mem, remaining, ok := find.Scalar(mesos.MEM, a.cfg.taskMem, remaining...)
+			taskResources, remaining, ok := request.Find(availableResources...)
 			if !ok {
 				break
 			}
@@ -182,7 +183,7 @@ func (a *app) handleOffers(offers []*mesos.Offer, flow flow.Flow) error {
 					Value:     nilIfEmptyString(a.cfg.taskCmd),
 					Arguments: a.cfg.taskArgs,
 				},
-				Resources: []*mesos.Resource{cpus, mem},
+				Resources: taskResources,
 			}
 
 			tasks = append(tasks, t)
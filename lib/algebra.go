@@ -0,0 +1,344 @@
+package mesos
+
+import (
+	"math"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// Returns true when resource has no value (zero scalar, no ranges or no set items)
+func (r *Resource) IsEmpty() bool {
+	switch r.GetType() {
+	case Value_SCALAR:
+		return r.ScalarValueOrZero() == 0
+	case Value_RANGES:
+		return len(r.RangesOrZero()) == 0
+	case Value_SET:
+		return len(r.GetSet().GetItem()) == 0
+	default:
+		return false
+	}
+}
+
+// Validates resource as done by Mesos master
+func (r *Resource) Validate() error {
+	if r.GetName() == "" {
+		return errors.New("Invalid resource: empty name")
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return errors.Errorf("Invalid resource %v: "+format, append([]interface{}{r.GetName()}, args...)...)
+	}
+
+	switch r.GetType() {
+	case Value_SCALAR:
+		if r.Scalar == nil || r.Ranges != nil || r.Set != nil {
+			return invalid("scalar resource must have only scalar value")
+		}
+		v := r.Scalar.GetValue()
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return invalid("scalar value %v is not a number", v)
+		}
+		if v < 0 {
+			return invalid("scalar value %v < 0", v)
+		}
+	case Value_RANGES:
+		if r.Ranges == nil || r.Scalar != nil || r.Set != nil {
+			return invalid("ranges resource must have only ranges value")
+		}
+		for _, rg := range r.RangesOrZero() {
+			if rg.GetBegin() > rg.GetEnd() {
+				return invalid("range [%v-%v] begin is greater than end", rg.GetBegin(), rg.GetEnd())
+			}
+		}
+		var size uint64
+		for _, rg := range r.RangesOrZero() {
+			size += rg.Len()
+		}
+		if size != r.RangesOrZero().Size() {
+			return invalid("overlapping ranges")
+		}
+	case Value_SET:
+		if r.Set == nil || r.Scalar != nil || r.Ranges != nil {
+			return invalid("set resource must have only set value")
+		}
+		seen := make(map[string]bool)
+		for _, i := range r.Set.GetItem() {
+			if seen[i] {
+				return invalid("duplicate set item %q", i)
+			}
+			seen[i] = true
+		}
+	default:
+		return invalid("unsupported type %v", r.GetType())
+	}
+
+	if r.Disk != nil && r.GetName() != string(DISK) {
+		return invalid("disk info can be set only for disk resource")
+	}
+
+	if r.Reservation != nil && r.GetRole() == Default_Resource_Role {
+		return invalid("role %q cannot be dynamically reserved", Default_Resource_Role)
+	}
+
+	if r.GetDisk().GetPersistence() != nil {
+		if r.GetRole() == Default_Resource_Role {
+			return invalid("persistent volume cannot be created from unreserved resource")
+		}
+		if r.Revocable != nil {
+			return invalid("persistent volume cannot be created from revocable resource")
+		}
+	}
+
+	if r.Shared != nil && r.GetDisk().GetPersistence() == nil {
+		return invalid("only persistent volumes can be shared")
+	}
+
+	return nil
+}
+
+// Validates all resources
+func (resources Resources) Validate() error {
+	for _, r := range resources {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true when resources contain no non-empty resource
+func (resources Resources) IsEmpty() bool {
+	for _, r := range resources {
+		if !r.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns sum of resources and that, resources with equal metadata are merged (scalars summed,
+// ranges and sets united). Invalid and empty resources are dropped. Neither resources nor that is modified.
+// Exclusive resources (persistent volumes and MOUNT disks) are never merged, shared resource is kept once for every share.
+func (resources Resources) Add(that ...*Resource) Resources {
+	res := Resources{}
+	for _, r := range resources {
+		res = res.add(r)
+	}
+	for _, r := range that {
+		res = res.add(r)
+	}
+	return res
+}
+
+// Returns resources without that. Exclusive resources are subtracted only when equal resource is subtracted.
+// Scalar subtraction resulting in negative value removes resource. Neither resources nor that is modified.
+func (resources Resources) Subtract(that ...*Resource) Resources {
+	res := Resources{}.Add(resources...)
+	for _, r := range that {
+		res = res.subtract(r)
+	}
+	return res
+}
+
+// Returns true when that is fully contained in resources
+func (resources Resources) Contains(that ...*Resource) bool {
+	remaining := Resources{}.Add(resources...)
+
+	for _, r := range that {
+		if r.Validate() != nil {
+			return false
+		}
+		if r.IsEmpty() {
+			continue
+		}
+
+		found := false
+		for _, left := range remaining {
+			if containsResource(left, r) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+
+		remaining = remaining.subtract(r)
+	}
+
+	return true
+}
+
+// Returns true when resources contain the same amount of equal resources as that
+func (resources Resources) Equals(that Resources) bool {
+	return resources.Contains(that...) && that.Contains(resources...)
+}
+
+// Returns resources with role and reservation set on all of them (nil reservation means no reservation)
+func (resources Resources) Flatten(role string, reservation *Resource_ReservationInfo) Resources {
+	flat := make(Resources, len(resources))
+	for i, r := range resources {
+		f := r.Clone()
+		f.Role = &role
+		f.Reservation = nil
+		if reservation != nil {
+			f.Reservation = proto.Clone(reservation).(*Resource_ReservationInfo)
+		}
+		flat[i] = f
+	}
+
+	return Resources{}.Add(flat...)
+}
+
+// must be called only on resources owned by caller
+func (resources Resources) add(r *Resource) Resources {
+	if r.Validate() != nil || r.IsEmpty() {
+		return resources
+	}
+
+	for _, left := range resources {
+		if addable(left, r) {
+			addValue(left, r)
+			return resources
+		}
+	}
+
+	c := r.Clone()
+	if c.GetType() == Value_RANGES {
+		c.Ranges.Range = c.RangesOrZero().Normalize()
+	}
+
+	return append(resources, c)
+}
+
+// must be called only on resources owned by caller
+func (resources Resources) subtract(r *Resource) Resources {
+	if r.Validate() != nil || r.IsEmpty() {
+		return resources
+	}
+
+	for i, left := range resources {
+		if !subtractable(left, r) {
+			continue
+		}
+
+		if !isExclusive(left) {
+			subtractValue(left, r)
+			if left.Validate() == nil && !left.IsEmpty() {
+				return resources
+			}
+		}
+
+		return append(resources[:i], resources[i+1:]...)
+	}
+
+	return resources
+}
+
+// Persistent volumes, disks with source other than PATH (MOUNT, BLOCK, RAW) and shared resources
+// can be only added or subtracted as a whole
+func isExclusive(r *Resource) bool {
+	d := r.GetDisk()
+	return r.Shared != nil || d.GetPersistence() != nil ||
+		(d.GetSource() != nil && d.GetSource().GetType() != Resource_DiskInfo_Source_PATH)
+}
+
+// Resources are of the same kind when all their fields except value are equal
+func sameKind(a, b *Resource) bool {
+	return a.GetName() == b.GetName() &&
+		a.GetType() == b.GetType() &&
+		a.GetRole() == b.GetRole() &&
+		proto.Equal(a.GetAllocationInfo(), b.GetAllocationInfo()) &&
+		proto.Equal(a.GetReservation(), b.GetReservation()) &&
+		proto.Equal(a.GetDisk(), b.GetDisk()) &&
+		(a.Revocable == nil) == (b.Revocable == nil) &&
+		(a.Shared == nil) == (b.Shared == nil) &&
+		proto.Equal(a.GetProviderId(), b.GetProviderId())
+}
+
+func addable(a, b *Resource) bool {
+	return sameKind(a, b) && !isExclusive(a)
+}
+
+func subtractable(a, b *Resource) bool {
+	if !sameKind(a, b) {
+		return false
+	}
+
+	if isExclusive(a) {
+		return proto.Equal(a, b)
+	}
+
+	return true
+}
+
+// Returns true when a contains b (both resources must be valid)
+func containsResource(a, b *Resource) bool {
+	if !subtractable(a, b) {
+		return false
+	}
+
+	switch a.GetType() {
+	case Value_SCALAR:
+		return ToFixed64(b.ScalarValueOrZero()) <= ToFixed64(a.ScalarValueOrZero())
+	case Value_RANGES:
+		return a.RangesOrZero().Includes(b.RangesOrZero())
+	case Value_SET:
+		items := make(map[string]bool)
+		for _, i := range a.GetSet().GetItem() {
+			items[i] = true
+		}
+		for _, i := range b.GetSet().GetItem() {
+			if !items[i] {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func addValue(a, b *Resource) {
+	switch a.GetType() {
+	case Value_SCALAR:
+		a.Scalar.Value = F64p(AddFixed(a.ScalarValueOrZero(), b.ScalarValueOrZero()))
+	case Value_RANGES:
+		a.Ranges.Range = a.RangesOrZero().Union(b.RangesOrZero())
+	case Value_SET:
+		items := make(map[string]bool)
+		for _, i := range a.Set.Item {
+			items[i] = true
+		}
+		for _, i := range b.GetSet().GetItem() {
+			if !items[i] {
+				a.Set.Item = append(a.Set.Item, i)
+				items[i] = true
+			}
+		}
+	}
+}
+
+func subtractValue(a, b *Resource) {
+	switch a.GetType() {
+	case Value_SCALAR:
+		a.Scalar.Value = F64p(SubtractFixed(a.ScalarValueOrZero(), b.ScalarValueOrZero()))
+	case Value_RANGES:
+		a.Ranges.Range = a.RangesOrZero().Difference(b.RangesOrZero())
+	case Value_SET:
+		remove := make(map[string]bool)
+		for _, i := range b.GetSet().GetItem() {
+			remove[i] = true
+		}
+		items := []string{}
+		for _, i := range a.Set.Item {
+			if !remove[i] {
+				items = append(items, i)
+			}
+		}
+		a.Set.Item = items
+	}
+}
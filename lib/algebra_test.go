@@ -0,0 +1,272 @@
+package mesos_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib"
+
+	"fmt"
+	"math/rand"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resources algebra", func() {
+
+	scalar := func(name ResourceName, v float64) *Resource {
+		return &Resource{Name: Strp(string(name)), Type: Value_SCALAR.Enum(), Scalar: &Value_Scalar{Value: &v}}
+	}
+
+	ranges := func(name ResourceName, r ...*Value_Range) *Resource {
+		return &Resource{Name: Strp(string(name)), Type: Value_RANGES.Enum(), Ranges: &Value_Ranges{Range: r}}
+	}
+
+	set := func(name ResourceName, items ...string) *Resource {
+		return &Resource{Name: Strp(string(name)), Type: Value_SET.Enum(), Set: &Value_Set{Item: items}}
+	}
+
+	reservation := func(principal string) *Resource_ReservationInfo {
+		return &Resource_ReservationInfo{Principal: Strp(principal)}
+	}
+
+	volume := func(id string, size float64) *Resource {
+		return scalar(DISK, size).
+			WithRole("role").
+			WithDisk(&Resource_DiskInfo{Persistence: &Resource_DiskInfo_Persistence{Id: Strp(id)}})
+	}
+
+	mount := func(root string, size float64) *Resource {
+		return scalar(DISK, size).WithDisk(&Resource_DiskInfo{
+			Source: &Resource_DiskInfo_Source{
+				Type:  Resource_DiskInfo_Source_MOUNT.Enum(),
+				Mount: &Resource_DiskInfo_Source_Mount{Root: Strp(root)},
+			},
+		})
+	}
+
+	It("Add merges resources with equal metadata", func() {
+		sum := Resources{scalar(CPUS, 1), scalar(MEM, 5)}.Add(scalar(CPUS, 2.5), scalar(MEM, 1).WithRole("role"))
+		Expect(sum).To(HaveLen(3))
+		Expect(sum.Equals(Resources{scalar(CPUS, 3.5), scalar(MEM, 5), scalar(MEM, 1).WithRole("role")})).To(BeTrue())
+
+		// fixed point arithmetic
+		Expect(Resources{scalar(CPUS, 0.1)}.Add(scalar(CPUS, 0.2))[0].ScalarValueOrZero()).To(Equal(0.3))
+
+		ports := Resources{ranges(PORTS, NewRange(1, 5))}.Add(ranges(PORTS, NewRange(6, 10), NewRange(20, 30), NewRange(3, 4)))
+		Expect(ports).To(HaveLen(1))
+		Expect(ports[0].RangesOrZero()).To(Equal(Ranges{NewRange(1, 10), NewRange(20, 30)}))
+
+		gpus := Resources{set("gpus", "a", "b")}.Add(set("gpus", "b", "c"))
+		Expect(gpus[0].GetSet().GetItem()).To(Equal([]string{"a", "b", "c"}))
+
+		// different reservation, revocable and allocation role are not merged
+		other := Resources{scalar(CPUS, 1).WithRole("role").WithReservation(reservation("a"))}.
+			Add(
+				scalar(CPUS, 1).WithRole("role").WithReservation(reservation("b")),
+				scalar(CPUS, 1).WithRevocable(&Resource_RevocableInfo{}),
+				scalar(CPUS, 1).WithAllocationRole("x"),
+				scalar(CPUS, 1),
+			)
+		Expect(other).To(HaveLen(5))
+	})
+
+	It("Add drops invalid and empty resources without modifying operands", func() {
+		left := Resources{scalar(CPUS, 1)}
+		right := Resources{scalar(CPUS, 2), scalar(MEM, 0), scalar(MEM, -1), set("gpus")}
+
+		sum := left.Add(right...)
+		Expect(sum).To(Equal(Resources{scalar(CPUS, 3)}))
+		Expect(left).To(Equal(Resources{scalar(CPUS, 1)}))
+		Expect(right[0]).To(Equal(scalar(CPUS, 2)))
+	})
+
+	It("Subtract", func() {
+		left := Resources{scalar(CPUS, 2), scalar(MEM, 1024), ranges(PORTS, NewRange(1, 10)), set("gpus", "a", "b")}
+
+		rem := left.Subtract(scalar(CPUS, 0.5), scalar(MEM, 1024), ranges(PORTS, NewRange(3, 4), NewRange(10, 12)), set("gpus", "b"))
+		Expect(rem.Equals(Resources{
+			scalar(CPUS, 1.5),
+			ranges(PORTS, NewRange(1, 2), NewRange(5, 9)),
+			set("gpus", "a"),
+		})).To(BeTrue())
+
+		// more than available
+		Expect(left.Subtract(scalar(CPUS, 3)).Contains(scalar(CPUS, 0.001))).To(BeFalse())
+		// different role is not subtracted
+		Expect(left.Subtract(scalar(CPUS, 1).WithRole("role")).Equals(left)).To(BeTrue())
+		Expect(left).To(HaveLen(4))
+	})
+
+	It("Exclusive resources are added and subtracted as a whole", func() {
+		vols := Resources{volume("1", 10)}.Add(volume("2", 10))
+		Expect(vols).To(HaveLen(2))
+		// same volume is never merged
+		Expect(Resources{volume("1", 10)}.Add(volume("1", 10))).To(HaveLen(2))
+
+		Expect(vols.Subtract(volume("1", 5)).Equals(vols)).To(BeTrue())
+		Expect(vols.Subtract(volume("1", 10)).Equals(Resources{volume("2", 10)})).To(BeTrue())
+		Expect(vols.Contains(volume("1", 5))).To(BeFalse())
+
+		mounts := Resources{mount("/mnt/a", 100)}.Add(mount("/mnt/a", 100))
+		Expect(mounts).To(HaveLen(2))
+		Expect(mounts.Subtract(mount("/mnt/a", 50))).To(HaveLen(2))
+		Expect(mounts.Subtract(mount("/mnt/a", 100))).To(HaveLen(1))
+
+		// BLOCK (3) and RAW (4) disks are not known to this version of protobuf
+		for _, typ := range []int32{3, 4} {
+			disk := scalar(DISK, 100).WithDisk(&Resource_DiskInfo{
+				Source: &Resource_DiskInfo_Source{Type: Resource_DiskInfo_Source_Type(typ).Enum()},
+			})
+			disks := Resources{disk}.Add(disk)
+			Expect(disks).To(HaveLen(2))
+			Expect(disks.Subtract(scalar(DISK, 50).WithDisk(disk.GetDisk()))).To(HaveLen(2))
+			Expect(disks.Subtract(disk)).To(HaveLen(1))
+		}
+
+		// PATH disks are merged and split
+		path := scalar(DISK, 100).WithDisk(&Resource_DiskInfo{
+			Source: &Resource_DiskInfo_Source{
+				Type: Resource_DiskInfo_Source_PATH.Enum(),
+				Path: &Resource_DiskInfo_Source_Path{Root: Strp("/mnt/b")},
+			},
+		})
+		paths := Resources{path}.Add(path)
+		Expect(paths).To(HaveLen(1))
+		Expect(paths.Subtract(scalar(DISK, 50).WithDisk(path.GetDisk()))[0].GetScalar().GetValue()).To(Equal(150.0))
+	})
+
+	It("Shared resources are counted per share", func() {
+		shared := volume("1", 10).WithShared(&Resource_SharedInfo{})
+
+		twice := Resources{shared}.Add(shared)
+		Expect(twice).To(HaveLen(2))
+		Expect(twice.Contains(shared, shared)).To(BeTrue())
+		Expect(twice.Contains(shared, shared, shared)).To(BeFalse())
+		Expect(twice.Subtract(shared).Equals(Resources{shared})).To(BeTrue())
+		Expect(twice.Equals(Resources{shared})).To(BeFalse())
+	})
+
+	It("Contains", func() {
+		left := Resources{scalar(CPUS, 2), ranges(PORTS, NewRange(1, 10)), set("gpus", "a", "b")}
+
+		Expect(left.Contains(scalar(CPUS, 1), scalar(CPUS, 1))).To(BeTrue())
+		Expect(left.Contains(scalar(CPUS, 1), scalar(CPUS, 1.001))).To(BeFalse())
+		Expect(left.Contains(ranges(PORTS, NewRange(2, 3), NewRange(9, 10)))).To(BeTrue())
+		Expect(left.Contains(ranges(PORTS, NewRange(9, 11)))).To(BeFalse())
+		Expect(left.Contains(set("gpus", "b"))).To(BeTrue())
+		Expect(left.Contains(set("gpus", "c"))).To(BeFalse())
+		Expect(left.Contains(scalar(CPUS, 1).WithRevocable(&Resource_RevocableInfo{}))).To(BeFalse())
+		Expect(left.Contains()).To(BeTrue())
+	})
+
+	It("Flatten", func() {
+		rs := Resources{
+			scalar(CPUS, 1),
+			scalar(CPUS, 2).WithRole("role"),
+			scalar(CPUS, 3).WithRole("role").WithReservation(reservation("p")),
+		}
+
+		Expect(rs.Flatten(Default_Resource_Role, nil).Equals(Resources{scalar(CPUS, 6)})).To(BeTrue())
+
+		flat := rs.Flatten("other", reservation("q"))
+		Expect(flat).To(HaveLen(1))
+		Expect(flat[0].GetRole()).To(Equal("other"))
+		Expect(flat[0].GetReservation().GetPrincipal()).To(Equal("q"))
+		Expect(rs[0].GetRole()).To(Equal(Default_Resource_Role))
+	})
+
+	It("IsEmpty", func() {
+		Expect(Resources{}.IsEmpty()).To(BeTrue())
+		Expect(Resources{scalar(CPUS, 0), ranges(PORTS), set("gpus")}.IsEmpty()).To(BeTrue())
+		Expect(Resources{scalar(CPUS, 0.1)}.IsEmpty()).To(BeFalse())
+	})
+
+	It("Validate", func() {
+		var tests = []struct {
+			r     *Resource
+			valid bool
+		}{
+			{scalar(CPUS, 1), true},
+			{scalar("", 1), false},
+			{scalar(CPUS, -1), false},
+			{&Resource{Name: Strp("cpus"), Type: Value_SCALAR.Enum()}, false},
+			{&Resource{Name: Strp("cpus"), Type: Value_SCALAR.Enum(), Scalar: &Value_Scalar{Value: F64p(1)}, Set: &Value_Set{}}, false},
+			{ranges(PORTS, NewRange(1, 10), NewRange(11, 20)), true},
+			{ranges(PORTS, NewRange(10, 1)), false},
+			{ranges(PORTS, NewRange(1, 10), NewRange(5, 20)), false},
+			{set("gpus", "a", "b"), true},
+			{set("gpus", "a", "a"), false},
+			{scalar(CPUS, 1).WithDisk(&Resource_DiskInfo{}), false},
+			{scalar(CPUS, 1).WithReservation(reservation("p")), false},
+			{scalar(CPUS, 1).WithRole("role").WithReservation(reservation("p")), true},
+			{volume("1", 10), true},
+			{volume("1", 10).WithRole(Default_Resource_Role), false},
+			{volume("1", 10).WithRevocable(&Resource_RevocableInfo{}), false},
+			{volume("1", 10).WithShared(&Resource_SharedInfo{}), true},
+			{scalar(DISK, 10).WithShared(&Resource_SharedInfo{}), false},
+		}
+
+		for i, tt := range tests {
+			err := tt.r.Validate()
+			Expect(err == nil).To(Equal(tt.valid), fmt.Sprintf("[%v] Validate %v: %v", i, tt.r, err))
+		}
+
+		Expect(Resources{scalar(CPUS, 1), set("gpus", "a", "a")}.Validate()).To(HaveOccurred())
+	})
+
+	Describe("Properties", func() {
+		rnd := rand.New(rand.NewSource(42))
+
+		genResource := func() *Resource {
+			var r *Resource
+			switch rnd.Intn(3) {
+			case 0:
+				r = scalar(CPUS, float64(rnd.Intn(10000))/1000)
+			case 1:
+				// overlapping ranges are merged by addition
+				var parts Resources
+				for i := rnd.Intn(3); i >= 0; i-- {
+					b := uint64(rnd.Intn(100))
+					parts = append(parts, ranges(PORTS, NewRange(b, b+uint64(rnd.Intn(10)))))
+				}
+				r = Resources{}.Add(parts...)[0]
+			default:
+				// duplicate items are removed by addition
+				var parts Resources
+				for i := rnd.Intn(4); i >= 0; i-- {
+					parts = append(parts, set("gpus", fmt.Sprint(rnd.Intn(6))))
+				}
+				r = Resources{}.Add(parts...)[0]
+			}
+
+			if rnd.Intn(3) == 0 {
+				r.WithRole("role")
+			}
+			return r
+		}
+
+		// resources are normalized as in Mesos (overlapping sets and ranges of the same kind are merged)
+		gen := func() Resources {
+			var res Resources
+			for i := rnd.Intn(5); i >= 0; i-- {
+				res = append(res, genResource())
+			}
+			return Resources{}.Add(res...)
+		}
+
+		It("Hold for random resources", func() {
+			for i := 0; i < 500; i++ {
+				a, b := gen(), gen()
+				sum := a.Add(b...)
+
+				Expect(sum.Validate()).To(Succeed())
+				Expect(sum.Equals(b.Add(a...))).To(BeTrue(), "a + b == b + a: %v, %v", a, b)
+				Expect(sum.Contains(a...)).To(BeTrue(), "a + b contains a: %v, %v", a, b)
+				Expect(sum.Contains(b...)).To(BeTrue(), "a + b contains b: %v, %v", a, b)
+				Expect(sum.Subtract(b...).Add(b...).Equals(sum)).To(BeTrue(), "(a + b) - b + b == a + b: %v, %v", a, b)
+				Expect(a.Contains(a.Subtract(b...)...)).To(BeTrue(), "a contains a - b: %v, %v", a, b)
+				Expect(a.Subtract(a...).IsEmpty()).To(BeTrue(), "a - a is empty: %v", a)
+				Expect(a.Equals(a.Add())).To(BeTrue(), "a == a + 0: %v", a)
+			}
+		})
+	})
+})
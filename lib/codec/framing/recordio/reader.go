@@ -48,6 +48,10 @@ func (rr *Reader) ReadFrame(p []byte) (endOfFrame bool, n int, err error) {
 		n += read
 		p = p[read:]
 		rr.pending -= uint64(read)
+		if rr.pending == 0 && n > 0 {
+			// Frame fully read (possibly filling whole buffer), report it before next header is read.
+			endOfFrame = true
+		}
 	}
 	return
 }
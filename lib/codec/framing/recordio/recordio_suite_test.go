@@ -65,6 +65,32 @@ var _ = Describe("RecordIOReader", func() {
 		}
 	})
 
+	It("Write and read frames", func() {
+		var buf bytes.Buffer
+		w := recordio.NewWriter(&buf)
+
+		frames := [][]byte{[]byte("a"), []byte("abcd"), []byte("xy")}
+		for _, f := range frames {
+			n, err := w.Write(f)
+			Expect(err).To(Succeed())
+			Expect(n).To(Equal(len(f)))
+		}
+		Expect(buf.String()).To(Equal("1\na4\nabcd2\nxy"))
+
+		r := recordio.New(&buf)
+		for _, f := range frames {
+			// buffer of exactly frame size must not merge following frame
+			p := make([]byte, len(f))
+			eof, n, err := r.ReadFrame(p)
+			Expect(err).To(Succeed())
+			Expect(eof).To(BeTrue())
+			Expect(p[:n]).To(Equal(f))
+		}
+
+		_, _, err := r.ReadFrame(make([]byte, 8))
+		Expect(err).To(Equal(io.EOF))
+	})
+
 	Measure("Benchmark", func(b Benchmarker) {
 		count := 100000
 		var buf bytes.Buffer
@@ -0,0 +1,24 @@
+package recordio
+
+import (
+	"io"
+	"strconv"
+)
+
+type Writer struct {
+	w io.Writer
+}
+
+// Every call to Write emits single frame (size header followed by data)
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+func (rw *Writer) Write(p []byte) (int, error) {
+	header := strconv.FormatUint(uint64(len(p)), 10) + "\n"
+	if _, err := io.WriteString(rw.w, header); err != nil {
+		return 0, err
+	}
+
+	return rw.w.Write(p)
+}
@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+)
+
+func (e *Call) Name() string {
+	return e.Type.String()
+}
+
+var _ = flow.Message(&Call{})
+
+func (e *Event) Name() string {
+	return e.Type.String()
+}
+
+var _ = flow.Message(&Event{})
+
+// Sends data to scheduler, Mesos does not guarantee delivery
+func Message(frameworkID, executorID string, data []byte) *Call {
+	return &Call{
+		Type:        Call_MESSAGE.Enum(),
+		FrameworkId: &mesos.FrameworkID{Value: &frameworkID},
+		ExecutorId:  &mesos.ExecutorID{Value: &executorID},
+		Message: &Call_Message{
+			Data: data,
+		},
+	}
+}
+
+// Returns true for message sent by scheduler
+func IsMessage(e flow.Message) (bool, *Event) {
+	switch r := e.(type) {
+	case *Event:
+		return r.GetType() == Event_MESSAGE, r
+	}
+
+	return false, nil
+}
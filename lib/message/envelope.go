@@ -0,0 +1,238 @@
+package message
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	golang_proto "github.com/golang/protobuf/proto"
+	"github.com/ondrej-smola/mesos-go-http/lib/executor"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+type (
+	Encoding int32
+
+	Opt func(e *Envelope)
+
+	// Payload exchanged between scheduler and executor using Call_MESSAGE and Event_MESSAGE.
+	// Hand written protobuf message, wire compatible with:
+	//
+	//	message Envelope {
+	//	  required string type_url = 1;
+	//	  optional string correlation_id = 2;
+	//	  optional string reply_to = 3; // correlation id of request
+	//	  optional bytes payload = 4;
+	//	  optional int32 encoding = 5;
+	//	}
+	Envelope struct {
+		TypeUrl       *string   `protobuf:"bytes,1,req,name=type_url" json:"type_url,omitempty"`
+		CorrelationId *string   `protobuf:"bytes,2,opt,name=correlation_id" json:"correlation_id,omitempty"`
+		ReplyTo       *string   `protobuf:"bytes,3,opt,name=reply_to" json:"reply_to,omitempty"`
+		Payload       []byte    `protobuf:"bytes,4,opt,name=payload" json:"payload,omitempty"`
+		Encoding      *Encoding `protobuf:"varint,5,opt,name=encoding" json:"encoding,omitempty"`
+	}
+)
+
+const (
+	ENCODING_PROTOBUF Encoding = iota
+	ENCODING_JSON
+)
+
+const TYPE_URL_PREFIX = "type.googleapis.com/"
+
+func (e Encoding) String() string {
+	switch e {
+	case ENCODING_PROTOBUF:
+		return "protobuf"
+	case ENCODING_JSON:
+		return "json"
+	default:
+		return "unknown"
+	}
+}
+
+func (e Encoding) Enum() *Encoding {
+	return &e
+}
+
+func (m *Envelope) Reset()         { *m = Envelope{} }
+func (m *Envelope) String() string { return proto.CompactTextString(m) }
+func (*Envelope) ProtoMessage()    {}
+
+func (m *Envelope) GetTypeUrl() string {
+	if m != nil && m.TypeUrl != nil {
+		return *m.TypeUrl
+	}
+	return ""
+}
+
+func (m *Envelope) GetCorrelationId() string {
+	if m != nil && m.CorrelationId != nil {
+		return *m.CorrelationId
+	}
+	return ""
+}
+
+func (m *Envelope) GetReplyTo() string {
+	if m != nil && m.ReplyTo != nil {
+		return *m.ReplyTo
+	}
+	return ""
+}
+
+func (m *Envelope) GetEncoding() Encoding {
+	if m != nil && m.Encoding != nil {
+		return *m.Encoding
+	}
+	return ENCODING_PROTOBUF
+}
+
+func WithCorrelationId(id string) Opt {
+	return func(e *Envelope) {
+		e.CorrelationId = &id
+	}
+}
+
+// Marks envelope as response to request
+func WithReplyTo(req *Envelope) Opt {
+	return func(e *Envelope) {
+		id := req.GetCorrelationId()
+		e.ReplyTo = &id
+	}
+}
+
+// Returns type URL of protobuf message, type name is used for unregistered messages
+func TypeUrl(m proto.Message) string {
+	name := proto.MessageName(m)
+	if name == "" {
+		// generated Mesos messages are registered in golang protobuf registry
+		name = golang_proto.MessageName(m)
+	}
+	if name == "" {
+		name = reflect.TypeOf(m).String()
+	}
+	return TYPE_URL_PREFIX + name
+}
+
+// Returns random correlation id
+func NewCorrelationId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("Failed to generate correlation id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Envelope with protobuf encoded message
+func New(m proto.Message, opts ...Opt) (*Envelope, error) {
+	payload, err := proto.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrap(err, "Envelope: marshal payload")
+	}
+
+	return newEnvelope(TypeUrl(m), payload, ENCODING_PROTOBUF, opts), nil
+}
+
+// Envelope with JSON encoded value, protobuf messages are encoded using jsonpb
+func NewJson(typeUrl string, v interface{}, opts ...Opt) (*Envelope, error) {
+	var payload []byte
+
+	if m, ok := v.(proto.Message); ok {
+		b := &bytes.Buffer{}
+		if err := (&jsonpb.Marshaler{}).Marshal(b, m); err != nil {
+			return nil, errors.Wrap(err, "Envelope: marshal payload")
+		}
+		payload = b.Bytes()
+	} else {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "Envelope: marshal payload")
+		}
+		payload = b
+	}
+
+	return newEnvelope(typeUrl, payload, ENCODING_JSON, opts), nil
+}
+
+func newEnvelope(typeUrl string, payload []byte, enc Encoding, opts []Opt) *Envelope {
+	e := &Envelope{
+		TypeUrl:  &typeUrl,
+		Payload:  payload,
+		Encoding: enc.Enum(),
+	}
+
+	for _, o := range opts {
+		o(e)
+	}
+
+	return e
+}
+
+// Decodes payload to v, v must be proto.Message for protobuf encoded payload
+func (m *Envelope) Decode(v interface{}) error {
+	switch m.GetEncoding() {
+	case ENCODING_PROTOBUF:
+		pm, ok := v.(proto.Message)
+		if !ok {
+			return errors.Errorf("Envelope: protobuf payload cannot be decoded to %T", v)
+		}
+		return errors.Wrap(proto.Unmarshal(m.Payload, pm), "Envelope: unmarshal payload")
+	case ENCODING_JSON:
+		if pm, ok := v.(proto.Message); ok {
+			err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(m.Payload), pm)
+			return errors.Wrap(err, "Envelope: unmarshal payload")
+		}
+		return errors.Wrap(json.Unmarshal(m.Payload, v), "Envelope: unmarshal payload")
+	default:
+		return errors.Errorf("Envelope: unknown encoding %v", m.GetEncoding())
+	}
+}
+
+func Unmarshal(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := proto.Unmarshal(data, e); err != nil {
+		return nil, errors.Wrap(err, "Envelope: unmarshal")
+	}
+	return e, nil
+}
+
+// Call sending envelope from scheduler to executor
+func SchedulerCall(agentId, executorId string, e *Envelope) (*scheduler.Call, error) {
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "Envelope: marshal")
+	}
+	return scheduler.Message(agentId, executorId, data), nil
+}
+
+// Returns envelope sent by executor
+func FromSchedulerEvent(ev *scheduler.Event) (*Envelope, error) {
+	if ev.GetType() != scheduler.Event_MESSAGE {
+		return nil, errors.Errorf("Envelope: expected %v event, got %v", scheduler.Event_MESSAGE, ev.GetType())
+	}
+	return Unmarshal(ev.GetMessage().GetData())
+}
+
+// Call sending envelope from executor to scheduler
+func ExecutorCall(frameworkId, executorId string, e *Envelope) (*executor.Call, error) {
+	data, err := proto.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "Envelope: marshal")
+	}
+	return executor.Message(frameworkId, executorId, data), nil
+}
+
+// Returns envelope sent by scheduler
+func FromExecutorEvent(ev *executor.Event) (*Envelope, error) {
+	if ev.GetType() != executor.Event_MESSAGE {
+		return nil, errors.Errorf("Envelope: expected %v event, got %v", executor.Event_MESSAGE, ev.GetType())
+	}
+	return Unmarshal(ev.GetMessage().GetData())
+}
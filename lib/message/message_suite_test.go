@@ -0,0 +1,96 @@
+package message_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/message"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/executor"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+func TestMessage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Message suite")
+}
+
+var _ = Describe("Envelope", func() {
+
+	It("Send protobuf message from scheduler to executor", func() {
+		taskId := &mesos.TaskID{Value: mesos.Strp("1")}
+		env, err := New(taskId, WithCorrelationId("c1"))
+		Expect(err).To(Succeed())
+		Expect(env.GetTypeUrl()).To(Equal("type.googleapis.com/mesos.v1.TaskID"))
+
+		call, err := SchedulerCall("agent", "executor", env)
+		Expect(err).To(Succeed())
+		Expect(call.GetType()).To(Equal(scheduler.Call_MESSAGE))
+		Expect(call.Message.AgentId.GetValue()).To(Equal("agent"))
+		Expect(call.Message.ExecutorId.GetValue()).To(Equal("executor"))
+
+		received, err := FromExecutorEvent(&executor.Event{
+			Type:    executor.Event_MESSAGE.Enum(),
+			Message: &executor.Event_Message{Data: call.Message.Data},
+		})
+		Expect(err).To(Succeed())
+		Expect(received).To(Equal(env))
+
+		decoded := &mesos.TaskID{}
+		Expect(received.Decode(decoded)).To(Succeed())
+		Expect(decoded).To(Equal(taskId))
+	})
+
+	It("Reply with JSON message from executor to scheduler", func() {
+		type status struct {
+			Healthy bool `json:"healthy"`
+		}
+
+		req, err := New(&mesos.TaskID{Value: mesos.Strp("1")}, WithCorrelationId("c1"))
+		Expect(err).To(Succeed())
+
+		env, err := NewJson("status", &status{Healthy: true}, WithReplyTo(req))
+		Expect(err).To(Succeed())
+		Expect(env.GetEncoding()).To(Equal(ENCODING_JSON))
+		Expect(env.GetReplyTo()).To(Equal("c1"))
+
+		call, err := ExecutorCall("fw", "executor", env)
+		Expect(err).To(Succeed())
+		Expect(call.GetType()).To(Equal(executor.Call_MESSAGE))
+		Expect(call.FrameworkId.GetValue()).To(Equal("fw"))
+
+		received, err := FromSchedulerEvent(&scheduler.Event{
+			Type: scheduler.Event_MESSAGE.Enum(),
+			Message: &scheduler.Event_Message{
+				AgentId:    &mesos.AgentID{Value: mesos.Strp("agent")},
+				ExecutorId: &mesos.ExecutorID{Value: mesos.Strp("executor")},
+				Data:       call.Message.Data,
+			},
+		})
+		Expect(err).To(Succeed())
+
+		decoded := &status{}
+		Expect(received.Decode(decoded)).To(Succeed())
+		Expect(decoded.Healthy).To(BeTrue())
+
+		Expect(received.Decode(&mesos.TaskID{})).To(HaveOccurred())
+	})
+
+	It("Encode protobuf message as JSON", func() {
+		taskId := &mesos.TaskID{Value: mesos.Strp("1")}
+		env, err := NewJson(TypeUrl(taskId), taskId)
+		Expect(err).To(Succeed())
+		Expect(string(env.Payload)).To(Equal(`{"value":"1"}`))
+
+		decoded := &mesos.TaskID{}
+		Expect(env.Decode(decoded)).To(Succeed())
+		Expect(decoded).To(Equal(taskId))
+	})
+
+	It("Reject non message events", func() {
+		_, err := FromSchedulerEvent(scheduler.TestHeartbeat())
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1,7 +1,12 @@
 package mesos
 
 import (
+	"bytes"
 	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 type Ranges []*Value_Range
@@ -52,3 +57,211 @@ func (r Ranges) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
 func (r Ranges) Less(i, j int) bool {
 	return r[i].GetBegin() < r[j].GetBegin() || (r[i].GetBegin() == r[j].GetBegin() && r[i].GetEnd() < r[j].GetEnd())
 }
+
+// Parses ranges in "[31000-32000,33000-33010]" format (single value "[80]" is accepted too), result is normalized
+func ParseRanges(s string) (Ranges, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, errors.Errorf("Ranges: %q must be enclosed in []", s)
+	}
+
+	body := strings.TrimSpace(s[1 : len(s)-1])
+	if body == "" {
+		return Ranges{}, nil
+	}
+
+	var res Ranges
+	for _, part := range strings.Split(body, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return nil, errors.Errorf("Ranges: invalid range %q in %q", part, s)
+		}
+
+		begin, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("Ranges: invalid range %q in %q: begin is not a number", part, s)
+		}
+
+		end := begin
+		if len(bounds) == 2 {
+			if end, err = strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 64); err != nil {
+				return nil, errors.Errorf("Ranges: invalid range %q in %q: end is not a number", part, s)
+			}
+		}
+
+		if begin > end {
+			return nil, errors.Errorf("Ranges: invalid range %q in %q: begin is greater than end", part, s)
+		}
+
+		res = append(res, NewRange(begin, end))
+	}
+
+	return res.Normalize(), nil
+}
+
+// Formats ranges as "[31000-32000,33000-33010]"
+func (r Ranges) String() string {
+	buf := bytes.Buffer{}
+	buf.WriteString("[")
+	for i, rg := range r {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(strconv.FormatUint(rg.GetBegin(), 10))
+		buf.WriteString("-")
+		buf.WriteString(strconv.FormatUint(rg.GetEnd(), 10))
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// Returns sorted ranges with overlapping and adjacent ranges merged, does not modify r
+func (r Ranges) Normalize() Ranges {
+	if len(r) == 0 {
+		return Ranges{}
+	}
+
+	sorted := r.Clone()
+	sorted.Sort()
+
+	res := Ranges{sorted[0]}
+	for _, cur := range sorted[1:] {
+		last := res[len(res)-1]
+		if cur.GetBegin() <= last.GetEnd() || cur.GetBegin() == last.GetEnd()+1 {
+			if cur.GetEnd() > last.GetEnd() {
+				last.End = UI64p(cur.GetEnd())
+			}
+		} else {
+			res = append(res, cur)
+		}
+	}
+
+	return res
+}
+
+// Returns true when ranges are sorted and contain no overlapping or adjacent ranges
+func (r Ranges) IsNormalized() bool {
+	for i, rg := range r {
+		if rg.GetBegin() > rg.GetEnd() {
+			return false
+		}
+		if i > 0 && (rg.GetBegin() <= r[i-1].GetEnd() || rg.GetBegin() == r[i-1].GetEnd()+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns number of values in ranges (overlapping values are counted once)
+func (r Ranges) Size() uint64 {
+	var size uint64
+	for _, rg := range r.Normalize() {
+		size += rg.Len()
+	}
+	return size
+}
+
+// Returns normalized union of r and o
+func (r Ranges) Union(o Ranges) Ranges {
+	return append(r.Clone(), o.Clone()...).Normalize()
+}
+
+// Returns normalized values of r not contained in o
+func (r Ranges) Difference(o Ranges) Ranges {
+	res := r.Normalize()
+	for _, s := range o.Normalize() {
+		next := Ranges{}
+		for _, rg := range res {
+			if s.GetEnd() < rg.GetBegin() || s.GetBegin() > rg.GetEnd() {
+				next = append(next, rg)
+				continue
+			}
+			if s.GetBegin() > rg.GetBegin() {
+				next = append(next, NewRange(rg.GetBegin(), s.GetBegin()-1))
+			}
+			if s.GetEnd() < rg.GetEnd() {
+				next = append(next, NewRange(s.GetEnd()+1, rg.GetEnd()))
+			}
+		}
+		res = next
+	}
+
+	return res
+}
+
+// Returns normalized values contained in both r and o
+func (r Ranges) Intersection(o Ranges) Ranges {
+	a, b := r.Normalize(), o.Normalize()
+	res := Ranges{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		begin, end := a[i].GetBegin(), a[i].GetEnd()
+		if b[j].GetBegin() > begin {
+			begin = b[j].GetBegin()
+		}
+		if b[j].GetEnd() < end {
+			end = b[j].GetEnd()
+		}
+		if begin <= end {
+			res = append(res, NewRange(begin, end))
+		}
+
+		if a[i].GetEnd() < b[j].GetEnd() {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return res
+}
+
+// Returns true when every value of o is contained in r
+func (r Ranges) Includes(o Ranges) bool {
+	return len(o.Difference(r)) == 0
+}
+
+// Returns true when value is contained in any range
+func (r Ranges) ContainsValue(v uint64) bool {
+	for _, rg := range r {
+		if rg.Contains(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns i-th smallest value of ranges (counted from 0)
+func (r Ranges) Nth(i uint64) (uint64, bool) {
+	for _, rg := range r.Normalize() {
+		if i < rg.Len() {
+			return rg.GetBegin() + i, true
+		}
+		i -= rg.Len()
+	}
+	return 0, false
+}
+
+// Calls f for every value in ascending order until f returns false
+func (r Ranges) Iterate(f func(v uint64) bool) {
+	for _, rg := range r.Normalize() {
+		for v := rg.GetBegin(); ; v++ {
+			if !f(v) {
+				return
+			}
+			if v == rg.GetEnd() {
+				break
+			}
+		}
+	}
+}
+
+// Returns ranges of single values
+func RangesOf(values ...uint64) Ranges {
+	res := make(Ranges, len(values))
+	for i, v := range values {
+		res[i] = NewRange(v, v)
+	}
+	return res
+}
@@ -4,6 +4,7 @@ import (
 	. "github.com/ondrej-smola/mesos-go-http/lib"
 
 	"fmt"
+	"math/rand"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -65,4 +66,149 @@ var _ = Describe("Ranges", func() {
 					i, tt.in, tt.what, ok, tt.ok))
 		}
 	})
+
+	It("Normalize", func() {
+		var tests = []struct {
+			in  Ranges
+			out Ranges
+		}{
+			{nil, Ranges{}},
+			{Ranges{NewRange(5, 9), NewRange(1, 2)}, Ranges{NewRange(1, 2), NewRange(5, 9)}},
+			{Ranges{NewRange(1, 5), NewRange(3, 9)}, Ranges{NewRange(1, 9)}},
+			{Ranges{NewRange(1, 5), NewRange(6, 9)}, Ranges{NewRange(1, 9)}},
+			{Ranges{NewRange(1, 10), NewRange(2, 3), NewRange(12, 12)}, Ranges{NewRange(1, 10), NewRange(12, 12)}},
+			{Ranges{NewRange(0, 0), NewRange(1, 1)}, Ranges{NewRange(0, 1)}},
+		}
+
+		for i, tt := range tests {
+			out := tt.in.Normalize()
+			Expect(out).To(Equal(tt.out), fmt.Sprintf("[%v] Normalize %v: %v (expected %v)", i, tt.in, out, tt.out))
+			Expect(out.IsNormalized()).To(BeTrue())
+		}
+
+		in := Ranges{NewRange(5, 9), NewRange(1, 6)}
+		in.Normalize()
+		Expect(in).To(Equal(Ranges{NewRange(5, 9), NewRange(1, 6)}))
+		Expect(in.IsNormalized()).To(BeFalse())
+	})
+
+	It("Set operations", func() {
+		a := Ranges{NewRange(1, 10), NewRange(20, 30)}
+		b := Ranges{NewRange(5, 22), NewRange(30, 40)}
+
+		Expect(a.Union(b)).To(Equal(Ranges{NewRange(1, 40)}))
+		Expect(a.Intersection(b)).To(Equal(Ranges{NewRange(5, 10), NewRange(20, 22), NewRange(30, 30)}))
+		Expect(a.Difference(b)).To(Equal(Ranges{NewRange(1, 4), NewRange(23, 29)}))
+		Expect(b.Difference(a)).To(Equal(Ranges{NewRange(11, 19), NewRange(31, 40)}))
+		Expect(a.Size()).To(BeEquivalentTo(21))
+		Expect(Ranges{NewRange(1, 5), NewRange(3, 7)}.Size()).To(BeEquivalentTo(7))
+		Expect(a.Includes(Ranges{NewRange(2, 3), NewRange(25, 30)})).To(BeTrue())
+		Expect(a.Includes(b)).To(BeFalse())
+		Expect(a.ContainsValue(20)).To(BeTrue())
+		Expect(a.ContainsValue(15)).To(BeFalse())
+	})
+
+	It("Iterate", func() {
+		r := Ranges{NewRange(7, 8), NewRange(1, 2)}
+
+		var values []uint64
+		r.Iterate(func(v uint64) bool {
+			values = append(values, v)
+			return true
+		})
+		Expect(values).To(Equal([]uint64{1, 2, 7, 8}))
+
+		values = nil
+		r.Iterate(func(v uint64) bool {
+			values = append(values, v)
+			return len(values) < 3
+		})
+		Expect(values).To(Equal([]uint64{1, 2, 7}))
+
+		v, ok := r.Nth(2)
+		Expect(ok).To(BeTrue())
+		Expect(v).To(BeEquivalentTo(7))
+		_, ok = r.Nth(4)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Parse and format", func() {
+		var tests = []struct {
+			in  string
+			out Ranges
+			ok  bool
+		}{
+			{"[31000-32000,33000-33010]", Ranges{NewRange(31000, 32000), NewRange(33000, 33010)}, true},
+			{" [ 1-2 , 80 ] ", Ranges{NewRange(1, 2), NewRange(80, 80)}, true},
+			{"[5-6,1-5]", Ranges{NewRange(1, 6)}, true},
+			{"[]", Ranges{}, true},
+			{"1-2", nil, false},
+			{"[2-1]", nil, false},
+			{"[a-2]", nil, false},
+			{"[1-2-3]", nil, false},
+			{"[1-]", nil, false},
+		}
+
+		for i, tt := range tests {
+			out, err := ParseRanges(tt.in)
+			Expect(err == nil).To(Equal(tt.ok), fmt.Sprintf("[%v] Parse %q: %v", i, tt.in, err))
+			Expect(out).To(Equal(tt.out), fmt.Sprintf("[%v] Parse %q: %v (expected %v)", i, tt.in, out, tt.out))
+		}
+
+		r := Ranges{NewRange(31000, 32000), NewRange(33000, 33010)}
+		Expect(r.String()).To(Equal("[31000-32000,33000-33010]"))
+		parsed, err := ParseRanges(r.String())
+		Expect(err).To(Succeed())
+		Expect(parsed).To(Equal(r))
+	})
+
+	It("Set operations match brute force", func() {
+		rnd := rand.New(rand.NewSource(7))
+
+		gen := func() (Ranges, map[uint64]bool) {
+			r := Ranges{}
+			values := make(map[uint64]bool)
+			for i := rnd.Intn(4); i > 0; i-- {
+				b := uint64(rnd.Intn(50))
+				e := b + uint64(rnd.Intn(8))
+				r = append(r, NewRange(b, e))
+				for v := b; v <= e; v++ {
+					values[v] = true
+				}
+			}
+			return r, values
+		}
+
+		toRanges := func(values map[uint64]bool) Ranges {
+			r := Ranges{}
+			for v := range values {
+				r = append(r, NewRange(v, v))
+			}
+			return r.Normalize()
+		}
+
+		for i := 0; i < 500; i++ {
+			a, av := gen()
+			b, bv := gen()
+
+			union, inter, diff := make(map[uint64]bool), make(map[uint64]bool), make(map[uint64]bool)
+			for v := range av {
+				union[v] = true
+				if bv[v] {
+					inter[v] = true
+				} else {
+					diff[v] = true
+				}
+			}
+			for v := range bv {
+				union[v] = true
+			}
+
+			Expect(a.Union(b)).To(Equal(toRanges(union)), "%v union %v", a, b)
+			Expect(a.Intersection(b)).To(Equal(toRanges(inter)), "%v intersection %v", a, b)
+			Expect(a.Difference(b)).To(Equal(toRanges(diff)), "%v difference %v", a, b)
+			Expect(a.Size()).To(BeEquivalentTo(len(av)))
+			Expect(a.Includes(b)).To(Equal(len(inter) == len(bv)))
+		}
+	})
 })
@@ -3,6 +3,7 @@ package mesos
 import (
 	"bytes"
 	"strconv"
+	"strings"
 )
 
 type Resources []*Resource
@@ -38,6 +39,17 @@ func (r *Resource) WithRole(role string) *Resource {
 	return r
 }
 
+// Sets role resource is allocated to (multi-role frameworks)
+func (r *Resource) WithAllocationRole(role string) *Resource {
+	r.AllocationInfo = &Resource_AllocationInfo{Role: &role}
+	return r
+}
+
+// Returns role resource is allocated to, empty when resource has no allocation info
+func (r *Resource) AllocationRole() string {
+	return r.GetAllocationInfo().GetRole()
+}
+
 func (r *Resource) WithReservation(ri *Resource_ReservationInfo) *Resource {
 	r.Reservation = ri
 	return r
@@ -58,6 +70,53 @@ func (r *Resource) WithRevocable(ri *Resource_RevocableInfo) *Resource {
 	return r
 }
 
+// Returns role offer is allocated to, allocation info of resources is used when offer has none
+func (o *Offer) AllocationRole() string {
+	if ai := o.GetAllocationInfo(); ai != nil {
+		return ai.GetRole()
+	}
+
+	for _, r := range o.GetResources() {
+		if role := r.AllocationRole(); role != "" {
+			return role
+		}
+	}
+
+	return ""
+}
+
+// Returns value of attribute formatted as in agent --attributes flag (text, scalar, [ranges] or {set})
+func (a *Attribute) ValueString() string {
+	switch a.GetType() {
+	case Value_SCALAR:
+		return strconv.FormatFloat(a.GetScalar().GetValue(), 'f', -1, 64)
+	case Value_RANGES:
+		return Ranges(a.GetRanges().GetRange()).String()
+	case Value_SET:
+		return "{" + strings.Join(a.GetSet().GetItem(), ",") + "}"
+	default:
+		return a.GetText().GetValue()
+	}
+}
+
+// Returns value of offer attribute, false when offer has no attribute of given name
+func (o *Offer) AttributeValue(name string) (string, bool) {
+	for _, a := range o.GetAttributes() {
+		if a.GetName() == name {
+			return a.ValueString(), true
+		}
+	}
+	return "", false
+}
+
+// Sets allocation role of all resources
+func (resources Resources) WithAllocationRole(role string) Resources {
+	for _, r := range resources {
+		r.WithAllocationRole(role)
+	}
+	return resources
+}
+
 func (resources Resources) String() string {
 	if len(resources) == 0 {
 		return ""
@@ -69,6 +128,11 @@ func (resources Resources) String() string {
 		}
 
 		buf.WriteString(r.GetName())
+		if ai := r.GetAllocationInfo(); ai != nil {
+			buf.WriteString("(allocated: ")
+			buf.WriteString(ai.GetRole())
+			buf.WriteString(")")
+		}
 		buf.WriteString("(")
 		buf.WriteString(r.GetRole())
 		if ri := r.GetReservation(); ri != nil {
@@ -105,16 +169,7 @@ func (resources Resources) String() string {
 		case Value_SCALAR:
 			buf.WriteString(strconv.FormatFloat(r.GetScalar().GetValue(), 'f', -1, 64))
 		case Value_RANGES:
-			buf.WriteString("[")
-			for j, r := range r.RangesOrZero() {
-				if j > 0 {
-					buf.WriteString(",")
-				}
-				buf.WriteString(strconv.FormatUint(r.GetBegin(), 10))
-				buf.WriteString("-")
-				buf.WriteString(strconv.FormatUint(r.GetEnd(), 10))
-			}
-			buf.WriteString("]")
+			buf.WriteString(r.RangesOrZero().String())
 		case Value_SET:
 			buf.WriteString("{")
 			items := r.GetSet().GetItem()
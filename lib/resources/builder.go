@@ -33,3 +33,13 @@ func NewRange(name mesos.ResourceName, ranges ...*mesos.Value_Range) *mesos.Reso
 		},
 	}
 }
+
+func NewSet(name mesos.ResourceName, items ...string) *mesos.Resource {
+	return &mesos.Resource{
+		Name: mesos.Strp(string(name)),
+		Type: mesos.Value_SET.Enum(),
+		Set: &mesos.Value_Set{
+			Item: items,
+		},
+	}
+}
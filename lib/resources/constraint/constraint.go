@@ -0,0 +1,271 @@
+package constraint
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/pkg/errors"
+)
+
+type (
+	Operator string
+
+	// Marathon style constraint on offer hostname or attribute, e.g. ["hostname", "UNIQUE"] or ["rack", "MAX_PER", "2"]
+	Constraint struct {
+		Field    string
+		Operator Operator
+		Value    string
+
+		re *regexp.Regexp
+	}
+
+	Constraints []Constraint
+
+	// Task running (or already placed) on agent, constraints are evaluated against set of tasks of single application
+	Task struct {
+		Hostname   string
+		Attributes []*mesos.Attribute
+	}
+
+	// Counts tasks by field values
+	Tracker struct {
+		fields map[string]map[string]int
+	}
+)
+
+const (
+	// Every task on different field value
+	UNIQUE = Operator("UNIQUE")
+	// All tasks on field value equal to constraint value (or to value of already running tasks when empty)
+	CLUSTER = Operator("CLUSTER")
+	// Tasks evenly distributed across field values, value is optional minimal number of distinct field values
+	GROUP_BY = Operator("GROUP_BY")
+	// Field value matches regular expression
+	LIKE = Operator("LIKE")
+	// Field value does not match regular expression
+	UNLIKE = Operator("UNLIKE")
+	// At most value tasks per field value
+	MAX_PER = Operator("MAX_PER")
+)
+
+// Field referring to offer hostname instead of attribute
+const HOSTNAME = "hostname"
+
+// Creates validated constraint
+func New(field string, op Operator, value string) (Constraint, error) {
+	c := Constraint{Field: field, Operator: op, Value: value}
+
+	if field == "" {
+		return c, errors.New("Constraint: empty field")
+	}
+
+	switch op {
+	case UNIQUE:
+		if value != "" {
+			return c, errors.Errorf("Constraint %v: %v does not take value", field, op)
+		}
+	case CLUSTER:
+	case GROUP_BY:
+		if value != "" {
+			if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+				return c, errors.Errorf("Constraint %v: %v value %q is not a number", field, op, value)
+			}
+		}
+	case MAX_PER:
+		if n, err := strconv.ParseUint(value, 10, 32); err != nil || n == 0 {
+			return c, errors.Errorf("Constraint %v: %v value %q is not a positive number", field, op, value)
+		}
+	case LIKE, UNLIKE:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return c, errors.Wrapf(err, "Constraint %v: %v", field, op)
+		}
+		c.re = re
+	default:
+		return c, errors.Errorf("Constraint %v: unknown operator %q", field, op)
+	}
+
+	return c, nil
+}
+
+// Same as New but panics on error
+func MustNew(field string, op Operator, value string) Constraint {
+	c, err := New(field, op, value)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Parses Marathon JSON syntax, either single constraint ["hostname","UNIQUE"]
+// or list of constraints [["hostname","UNIQUE"],["rack","LIKE","rack-[1-3]"]]
+func Parse(text string) (Constraints, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, errors.Wrap(err, "Constraint: invalid JSON")
+	}
+
+	if len(raw) > 0 && bytes.HasPrefix(bytes.TrimSpace(raw[0]), []byte(`"`)) {
+		c, err := parseOne(text)
+		if err != nil {
+			return nil, err
+		}
+		return Constraints{c}, nil
+	}
+
+	res := make(Constraints, len(raw))
+	for i, r := range raw {
+		c, err := parseOne(string(r))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Constraint %v", i+1)
+		}
+		res[i] = c
+	}
+
+	return res, nil
+}
+
+func parseOne(text string) (Constraint, error) {
+	var parts []string
+	if err := json.Unmarshal([]byte(text), &parts); err != nil {
+		return Constraint{}, errors.Wrapf(err, "Constraint: %v is not array of strings", text)
+	}
+
+	switch len(parts) {
+	case 2:
+		return New(parts[0], Operator(parts[1]), "")
+	case 3:
+		return New(parts[0], Operator(parts[1]), parts[2])
+	default:
+		return Constraint{}, errors.Errorf("Constraint: %v must have 2 or 3 elements", text)
+	}
+}
+
+// Returns constraint in JSON syntax
+func (c Constraint) String() string {
+	b, _ := c.MarshalJSON()
+	return string(b)
+}
+
+func (c Constraint) MarshalJSON() ([]byte, error) {
+	parts := []string{c.Field, string(c.Operator)}
+	if c.Value != "" {
+		parts = append(parts, c.Value)
+	}
+	return json.Marshal(parts)
+}
+
+// Returns true when task can be placed on offer given tasks tracked by t (nil tracker means no tasks)
+func (c Constraint) Accepts(o *mesos.Offer, t *Tracker) bool {
+	value, ok := fieldOfOffer(o, c.Field)
+	if !ok {
+		// Marathon accepts offers without field only for UNLIKE
+		return c.Operator == UNLIKE
+	}
+
+	counts := t.values(c.Field)
+
+	switch c.Operator {
+	case UNIQUE:
+		return counts[value] == 0
+	case CLUSTER:
+		if c.Value != "" {
+			return value == c.Value
+		}
+		for v, n := range counts {
+			if n > 0 && v != value {
+				return false
+			}
+		}
+		return true
+	case GROUP_BY:
+		min := 0
+		if groups, _ := strconv.Atoi(c.Value); len(counts) >= groups && len(counts) > 0 {
+			min = -1
+			for _, n := range counts {
+				if min < 0 || n < min {
+					min = n
+				}
+			}
+		}
+		return counts[value] <= min
+	case MAX_PER:
+		max, _ := strconv.Atoi(c.Value)
+		return counts[value] < max
+	case LIKE, UNLIKE:
+		re := c.re
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile("^(?:" + c.Value + ")$"); err != nil {
+				return false
+			}
+		}
+		return re.MatchString(value) == (c.Operator == LIKE)
+	default:
+		return false
+	}
+}
+
+// Returns true when all constraints accept offer
+func (cs Constraints) Accepts(o *mesos.Offer, t *Tracker) bool {
+	for _, c := range cs {
+		if !c.Accepts(o, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns task placed on offer
+func TaskOn(o *mesos.Offer) Task {
+	return Task{Hostname: o.GetHostname(), Attributes: o.GetAttributes()}
+}
+
+func NewTracker(tasks ...Task) *Tracker {
+	t := &Tracker{fields: make(map[string]map[string]int)}
+	t.Add(tasks...)
+	return t
+}
+
+// Adds tasks to tracker
+func (t *Tracker) Add(tasks ...Task) {
+	for _, task := range tasks {
+		t.inc(HOSTNAME, task.Hostname)
+		for _, a := range task.Attributes {
+			if a.GetName() != HOSTNAME {
+				t.inc(a.GetName(), a.ValueString())
+			}
+		}
+	}
+}
+
+// Returns number of tasks with field value
+func (t *Tracker) Count(field, value string) int {
+	return t.values(field)[value]
+}
+
+func (t *Tracker) inc(field, value string) {
+	values, ok := t.fields[field]
+	if !ok {
+		values = make(map[string]int)
+		t.fields[field] = values
+	}
+	values[value]++
+}
+
+func (t *Tracker) values(field string) map[string]int {
+	if t == nil {
+		return nil
+	}
+	return t.fields[field]
+}
+
+func fieldOfOffer(o *mesos.Offer, field string) (string, bool) {
+	if field == HOSTNAME {
+		return o.GetHostname(), true
+	}
+	return o.AttributeValue(field)
+}
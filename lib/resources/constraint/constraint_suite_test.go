@@ -0,0 +1,13 @@
+package constraint_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Constraint Suite")
+}
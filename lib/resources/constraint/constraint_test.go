@@ -0,0 +1,122 @@
+package constraint_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources/constraint"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func offer(hostname string, attrs ...string) *mesos.Offer {
+	o := &mesos.Offer{Hostname: mesos.Strp(hostname)}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		o.Attributes = append(o.Attributes, &mesos.Attribute{
+			Name: mesos.Strp(attrs[i]),
+			Type: mesos.Value_TEXT.Enum(),
+			Text: &mesos.Value_Text{Value: mesos.Strp(attrs[i+1])},
+		})
+	}
+	return o
+}
+
+var _ = Describe("Constraint", func() {
+
+	It("Parse", func() {
+		cs, err := Parse(`["hostname", "UNIQUE"]`)
+		Expect(err).To(Succeed())
+		Expect(cs).To(HaveLen(1))
+		Expect(cs[0].Field).To(Equal(HOSTNAME))
+		Expect(cs[0].Operator).To(Equal(UNIQUE))
+
+		cs, err = Parse(`[["hostname","UNIQUE"],["rack","GROUP_BY","3"],["rack","LIKE","rack-[1-3]"]]`)
+		Expect(err).To(Succeed())
+		Expect(cs).To(HaveLen(3))
+		Expect(cs[1].Operator).To(Equal(GROUP_BY))
+		Expect(cs[1].Value).To(Equal("3"))
+
+		b, err := json.Marshal(cs)
+		Expect(err).To(Succeed())
+		Expect(string(b)).To(Equal(`[["hostname","UNIQUE"],["rack","GROUP_BY","3"],["rack","LIKE","rack-[1-3]"]]`))
+		Expect(cs[2].String()).To(Equal(`["rack","LIKE","rack-[1-3]"]`))
+
+		cs, err = Parse(`[]`)
+		Expect(err).To(Succeed())
+		Expect(cs).To(BeEmpty())
+
+		var invalid = []struct {
+			in  string
+			err string
+		}{
+			{`["hostname"]`, "must have 2 or 3 elements"},
+			{`["hostname", "UNIQUE", "1", "2"]`, "must have 2 or 3 elements"},
+			{`["hostname", "NEAR"]`, "unknown operator"},
+			{`["hostname", "UNIQUE", "x"]`, "does not take value"},
+			{`["rack", "MAX_PER", "0"]`, "not a positive number"},
+			{`["rack", "GROUP_BY", "x"]`, "not a number"},
+			{`["rack", "LIKE", "("]`, "LIKE"},
+			{`["", "UNIQUE"]`, "empty field"},
+			{`[["hostname", "UNIQUE"], [1]]`, "Constraint 2"},
+			{`{"hostname": "UNIQUE"}`, "invalid JSON"},
+		}
+
+		for i, tt := range invalid {
+			_, err := Parse(tt.in)
+			Expect(err).To(HaveOccurred(), fmt.Sprintf("[%v] Parse %v", i, tt.in))
+			Expect(err.Error()).To(ContainSubstring(tt.err), fmt.Sprintf("[%v] Parse %v", i, tt.in))
+		}
+	})
+
+	It("Accepts", func() {
+		running := NewTracker(
+			TaskOn(offer("h1", "rack", "r1")),
+			TaskOn(offer("h2", "rack", "r1")),
+			TaskOn(offer("h3", "rack", "r2")),
+		)
+
+		var tests = []struct {
+			c      Constraint
+			offer  *mesos.Offer
+			accept bool
+		}{
+			{MustNew(HOSTNAME, UNIQUE, ""), offer("h1"), false},
+			{MustNew(HOSTNAME, UNIQUE, ""), offer("h4"), true},
+			{MustNew("rack", UNIQUE, ""), offer("h4", "rack", "r2"), false},
+			{MustNew("rack", UNIQUE, ""), offer("h4", "rack", "r3"), true},
+			{MustNew("rack", UNIQUE, ""), offer("h4"), false},
+			{MustNew("rack", CLUSTER, "r2"), offer("h4", "rack", "r2"), true},
+			{MustNew("rack", CLUSTER, "r2"), offer("h4", "rack", "r1"), false},
+			// running tasks are already on two racks
+			{MustNew("rack", CLUSTER, ""), offer("h4", "rack", "r1"), false},
+			{MustNew("rack", GROUP_BY, ""), offer("h4", "rack", "r1"), false},
+			{MustNew("rack", GROUP_BY, ""), offer("h4", "rack", "r2"), true},
+			{MustNew("rack", GROUP_BY, ""), offer("h4", "rack", "r3"), true},
+			// 3 groups expected but only 2 are used
+			{MustNew("rack", GROUP_BY, "3"), offer("h4", "rack", "r2"), false},
+			{MustNew("rack", GROUP_BY, "3"), offer("h4", "rack", "r3"), true},
+			{MustNew("rack", LIKE, "r[1-2]"), offer("h4", "rack", "r2"), true},
+			{MustNew("rack", LIKE, "r[1-2]"), offer("h4", "rack", "r22"), false},
+			{MustNew("rack", LIKE, "r.*"), offer("h4"), false},
+			{MustNew("rack", UNLIKE, "r[1-2]"), offer("h4", "rack", "r2"), false},
+			{MustNew("rack", UNLIKE, "r[1-2]"), offer("h4", "rack", "r3"), true},
+			{MustNew("rack", UNLIKE, "r[1-2]"), offer("h4"), true},
+			{MustNew("rack", MAX_PER, "2"), offer("h4", "rack", "r1"), false},
+			{MustNew("rack", MAX_PER, "2"), offer("h4", "rack", "r2"), true},
+			{MustNew(HOSTNAME, MAX_PER, "1"), offer("h3"), false},
+			// zero value constraint compiles expression on demand
+			{Constraint{Field: HOSTNAME, Operator: LIKE, Value: "h[0-9]"}, offer("h4"), true},
+		}
+
+		for i, tt := range tests {
+			Expect(tt.c.Accepts(tt.offer, running)).To(Equal(tt.accept),
+				fmt.Sprintf("[%v] %v accepts %v on %v", i, tt.c, tt.offer, running))
+		}
+
+		Expect(Constraints{MustNew(HOSTNAME, UNIQUE, ""), MustNew("rack", UNIQUE, "")}.Accepts(offer("h4", "rack", "r1"), running)).To(BeFalse())
+		Expect(Constraints{MustNew(HOSTNAME, UNIQUE, "")}.Accepts(offer("h1"), nil)).To(BeTrue())
+		Expect(running.Count("rack", "r1")).To(Equal(2))
+		Expect(running.Count(HOSTNAME, "h3")).To(Equal(1))
+	})
+})
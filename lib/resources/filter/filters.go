@@ -24,6 +24,20 @@ func Role(role string) Filter {
 	})
 }
 
+// Resources allocated to role (multi-role frameworks)
+func AllocationRole(role string) Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		return r.AllocationRole() == role
+	})
+}
+
+// Resources carrying allocation info
+func Allocated() Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		return r.GetAllocationInfo() != nil
+	})
+}
+
 func Scalar() Filter {
 	return FilterFunc(func(r *mesos.Resource) bool {
 		return r.GetType() == mesos.Value_SCALAR
@@ -92,6 +106,66 @@ func Disk() Filter {
 	return Name(mesos.DISK)
 }
 
+// Disk source types added in Mesos 1.5 and missing in generated protobuf of this library,
+// source type of resources offered by newer masters keeps its numeric value when decoded
+const (
+	DISK_SOURCE_BLOCK = mesos.Resource_DiskInfo_Source_Type(3)
+	DISK_SOURCE_RAW   = mesos.Resource_DiskInfo_Source_Type(4)
+)
+
+// Disk without source (agent work directory)
+func RootDisk() Filter {
+	return And(Disk(), FilterFunc(func(r *mesos.Resource) bool {
+		return r.GetDisk().GetSource() == nil
+	}))
+}
+
+// Disk with source of given type
+func DiskSource(t mesos.Resource_DiskInfo_Source_Type) Filter {
+	return And(Disk(), FilterFunc(func(r *mesos.Resource) bool {
+		s := r.GetDisk().GetSource()
+		return s != nil && s.GetType() == t
+	}))
+}
+
+func PathDisk() Filter {
+	return DiskSource(mesos.Resource_DiskInfo_Source_PATH)
+}
+
+// MOUNT disks can be used only as a whole
+func MountDisk() Filter {
+	return DiskSource(mesos.Resource_DiskInfo_Source_MOUNT)
+}
+
+func BlockDisk() Filter {
+	return DiskSource(DISK_SOURCE_BLOCK)
+}
+
+func RawDisk() Filter {
+	return DiskSource(DISK_SOURCE_RAW)
+}
+
+func PersistentVolume() Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		return r.GetDisk().GetPersistence() != nil
+	})
+}
+
+// Persistent volume with persistence id
+func PersistentVolumeId(id string) Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		p := r.GetDisk().GetPersistence()
+		return p != nil && p.GetId() == id
+	})
+}
+
+// Resources that can be used by multiple tasks at once (shared persistent volumes)
+func Shared() Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		return r.Shared != nil
+	})
+}
+
 func Revocable() Filter {
 	return FilterFunc(func(r *mesos.Resource) bool {
 		return r.Revocable != nil
@@ -102,17 +176,42 @@ func Unreserved() Filter {
 	return Role(mesos.Default_Resource_Role)
 }
 
-func StaticallyReserved(r *mesos.Resource) Filter {
-	and := And(Not(Unreserved()), Not(DynamicallyReserved()))
+// Resources reserved for role by agent configuration
+func StaticallyReserved() Filter {
+	return And(Not(Unreserved()), Not(DynamicallyReserved()))
+}
+
+func DynamicallyReserved() Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		return r.GetReservation() != nil
+	})
+}
+
+// Dynamically reserved resources reserved by principal
+func ReservationPrincipal(principal string) Filter {
+	return FilterFunc(func(r *mesos.Resource) bool {
+		ri := r.GetReservation()
+		return ri != nil && ri.GetPrincipal() == principal
+	})
+}
 
+// Dynamically reserved resources with reservation label
+func ReservationLabel(key, value string) Filter {
 	return FilterFunc(func(r *mesos.Resource) bool {
-		return and.Accepts(r)
+		for _, l := range r.GetReservation().GetLabels().GetLabels() {
+			if l.GetKey() == key {
+				return l.GetValue() == value
+			}
+		}
+		return false
 	})
 }
 
-func DynamicallyReserved() Filter {
+// Resources provided by resource provider
+func ProviderId(id string) Filter {
 	return FilterFunc(func(r *mesos.Resource) bool {
-		return r.GetReservation() != nil
+		p := r.GetProviderId()
+		return p != nil && p.GetValue() == id
 	})
 }
 
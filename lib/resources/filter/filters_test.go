@@ -0,0 +1,79 @@
+package filter_test
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filters", func() {
+
+	source := func(t mesos.Resource_DiskInfo_Source_Type) *mesos.Resource {
+		return resources.NewScalar(mesos.DISK, 100).WithDisk(&mesos.Resource_DiskInfo{
+			Source: &mesos.Resource_DiskInfo_Source{Type: t.Enum()},
+		})
+	}
+
+	It("Select resources", func() {
+		var (
+			cpus      = resources.Cpus(1)
+			static    = resources.Cpus(1).WithRole("r")
+			dynamic   = resources.Reservation{Role: "r", Principal: "p", Labels: map[string]string{"k": "v"}}.Reserve(resources.Cpus(1))[0]
+			revocable = resources.Cpus(1).WithRevocable(&mesos.Resource_RevocableInfo{})
+			provided  = resources.Cpus(1)
+			root      = resources.NewScalar(mesos.DISK, 100)
+			path      = source(mesos.Resource_DiskInfo_Source_PATH)
+			mount     = source(mesos.Resource_DiskInfo_Source_MOUNT)
+			block     = source(filter.DISK_SOURCE_BLOCK)
+			raw       = source(filter.DISK_SOURCE_RAW)
+			volume    = resources.Volume{Id: "v1", ContainerPath: "data"}.Create(resources.Reservation{Role: "r"}.Reserve(root)[0])
+			shared    = volume.Clone().WithShared(&mesos.Resource_SharedInfo{})
+		)
+		provided.ProviderId = &mesos.ResourceProviderID{Value: mesos.Strp("rp")}
+
+		all := mesos.Resources{cpus, static, dynamic, revocable, provided, root, path, mount, block, raw, volume, shared}
+
+		var tests = []struct {
+			name   string
+			filter filter.Filter
+			expect mesos.Resources
+		}{
+			{"StaticallyReserved", filter.StaticallyReserved(), mesos.Resources{static}},
+			{"DynamicallyReserved", filter.DynamicallyReserved(), mesos.Resources{dynamic, volume, shared}},
+			{"Revocable", filter.Revocable(), mesos.Resources{revocable}},
+			{"ProviderId", filter.ProviderId("rp"), mesos.Resources{provided}},
+			{"ProviderId other", filter.ProviderId("other"), nil},
+			{"RootDisk", filter.RootDisk(), mesos.Resources{root, volume, shared}},
+			{"PathDisk", filter.PathDisk(), mesos.Resources{path}},
+			{"MountDisk", filter.MountDisk(), mesos.Resources{mount}},
+			{"BlockDisk", filter.BlockDisk(), mesos.Resources{block}},
+			{"RawDisk", filter.RawDisk(), mesos.Resources{raw}},
+			{"PersistentVolume", filter.PersistentVolume(), mesos.Resources{volume, shared}},
+			{"PersistentVolumeId", filter.PersistentVolumeId("v1"), mesos.Resources{volume, shared}},
+			{"PersistentVolumeId other", filter.PersistentVolumeId("v2"), nil},
+			{"Shared", filter.Shared(), mesos.Resources{shared}},
+			{"ReservationPrincipal", filter.ReservationPrincipal("p"), mesos.Resources{dynamic}},
+			{"ReservationLabel", filter.ReservationLabel("k", "v"), mesos.Resources{dynamic}},
+			{"ReservationLabel other value", filter.ReservationLabel("k", "x"), nil},
+		}
+
+		for i, tt := range tests {
+			Expect(filter.All(tt.filter, all...)).To(Equal(tt.expect), fmt.Sprintf("[%v] %v", i, tt.name))
+		}
+	})
+
+	It("Keep disk source types unknown to generated protobuf", func() {
+		b, err := proto.Marshal(source(filter.DISK_SOURCE_BLOCK))
+		Expect(err).To(Succeed())
+
+		decoded := &mesos.Resource{}
+		Expect(proto.Unmarshal(b, decoded)).To(Succeed())
+		Expect(filter.BlockDisk().Accepts(decoded)).To(BeTrue())
+	})
+})
@@ -1,16 +1,19 @@
 package find
 
 import (
-	"math/rand"
-	"sort"
-
 	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
 )
 
-// overwrite only in init()
-var RandFunc = func(i int) int {
-	return rand.Intn(i)
+var defaultFinder = New()
+
+// Splits resources to those allocated to role and all others (multi-role frameworks).
+// Resources found in allocated resources keep allocation info and can be used to launch task on offer of role.
+func AllocatedTo(role string, in ...*mesos.Resource) (mesos.Resources, mesos.Resources) {
+	res := mesos.Resources(in).Clone()
+	f := filter.AllocationRole(role)
+
+	return filter.All(f, res...), filter.All(filter.Not(f), res...)
 }
 
 func Scalar(name mesos.ResourceName, value float64, in ...*mesos.Resource) (*mesos.Resource, mesos.Resources, bool) {
@@ -33,153 +36,168 @@ func Scalar(name mesos.ResourceName, value float64, in ...*mesos.Resource) (*mes
 	return cpus, remaining, true
 }
 
-// select random element from random range in provided resources
+// Selects random value (uniformly over all values) of first non-empty ranges resource using global math/rand source,
+// use Finder for seeded or deterministic selection
 func RandomInRange(name mesos.ResourceName, in ...*mesos.Resource) (*mesos.Resource, mesos.Resources, bool) {
-	allResources := mesos.Resources(in).Clone()
+	return defaultFinder.InRange(name, in...)
+}
+
+// Takes all values from ranges resources of given name, values may be spread over multiple resources.
+// Fails when any value is not available (or is requested more than once).
+func ValuesInRange(name mesos.ResourceName, values []uint64, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	res := mesos.Resources(in).Clone()
+
 	f := filter.And(filter.Name(name), filter.Range())
 
-	ranges := filter.All(f, allResources...)
-	others := filter.All(filter.Not(f), allResources...)
+	ranges := filter.All(f, res...)
+	rem := filter.All(filter.Not(f), res...)
+
+	toFind := mesos.RangesOf(values...).Normalize()
+	if toFind.Size() != uint64(len(values)) {
+		// duplicate values
+		return nil, mesos.Resources(in), false
+	}
+
+	takeRanges := mesos.Resources{}
 
 	for i, r := range ranges {
+		if len(toFind) == 0 {
+			// append all other
+			rem = append(rem, ranges[i:]...)
+			break
+		}
+
 		resRanges := r.RangesOrZero()
-		if resRanges.Len() == 0 {
-			others = append(others, ranges[i])
-			continue
+
+		if take := resRanges.Intersection(toFind); len(take) > 0 {
+			// create clone with ranges to use
+			cl := r.Clone()
+			cl.Ranges = &mesos.Value_Ranges{Range: take}
+			takeRanges = append(takeRanges, cl)
+
+			toFind = toFind.Difference(take)
+			resRanges = resRanges.Difference(take)
 		}
 
-		// select random range
-		idx := RandFunc(resRanges.Len())
-		// range containing selected element
-		selectedRange := resRanges[idx].Clone()
-		// all other ranges
-		notSelectedRanges := r.Clone()
-
-		if selectedRange.Len() == 1 {
-			// take whole selected range, and rest is not selected
-			notSelectedRanges.Ranges = &mesos.Value_Ranges{Range: append(resRanges[:idx], resRanges[idx+1:]...)}
-		} else {
-			// selected random element in range
-			selectedElement := selectedRange.GetBegin() + uint64(RandFunc(int(selectedRange.Len())))
-
-			leftSplit, rightSplit, _ := selectedRange.Split(selectedElement)
-			// all other ranges are not selected
-			remRanges := resRanges[:idx].Clone()
-			if leftSplit != nil {
-				remRanges = append(remRanges, leftSplit)
-			}
+		if len(resRanges) > 0 {
+			// create clone with ranges to skip
+			cl := r.Clone()
+			cl.Ranges = &mesos.Value_Ranges{Range: resRanges}
+			rem = append(rem, cl)
+		}
+	}
 
-			if rightSplit != nil {
-				remRanges = append(remRanges, rightSplit)
-			}
-			notSelectedRanges.Ranges = &mesos.Value_Ranges{Range: append(remRanges, resRanges[idx+1:]...)}
+	if len(toFind) == 0 {
+		return takeRanges, rem, true
+	} else {
+		return nil, mesos.Resources(in), false
+	}
+}
+
+// Takes n items from set resources of given name, items may be spread over multiple resources
+// (taken in order of resources and items). Fails when there are less than n items available.
+func SetItems(name mesos.ResourceName, n int, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	res := mesos.Resources(in).Clone()
 
-			// set selected range to contain only selected element
-			selectedRange.Begin = mesos.UI64p(selectedElement)
-			selectedRange.End = mesos.UI64p(selectedElement)
+	f := filter.And(filter.Name(name), filter.Set())
+
+	sets := filter.All(f, res...)
+	rem := filter.All(filter.Not(f), res...)
+
+	take := mesos.Resources{}
+
+	for i, r := range sets {
+		if n <= 0 {
+			rem = append(rem, sets[i:]...)
+			break
 		}
 
-		// append current not selected ranges and also all other resources
-		others = append(others, notSelectedRanges)
-		others = append(others, ranges[i+1:]...)
+		items := r.GetSet().GetItem()
+		k := n
+		if len(items) < k {
+			k = len(items)
+		}
+		if k == 0 {
+			rem = append(rem, r)
+			continue
+		}
+
+		cl := r.Clone()
+		cl.Set = &mesos.Value_Set{Item: append([]string{}, items[:k]...)}
+		take = append(take, cl)
 
-		found := r.Clone()
-		found.Ranges = &mesos.Value_Ranges{Range: []*mesos.Value_Range{selectedRange}}
+		if k < len(items) {
+			cl := r.Clone()
+			cl.Set = &mesos.Value_Set{Item: append([]string{}, items[k:]...)}
+			rem = append(rem, cl)
+		}
 
-		return found, others, true
+		n -= k
 	}
 
-	return nil, mesos.Resources(in), false
+	if n <= 0 {
+		return take, rem, true
+	} else {
+		return nil, mesos.Resources(in), false
+	}
 }
 
-func ValuesInRange(name mesos.ResourceName, values []uint64, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+// Takes all values from set resources of given name, values may be spread over multiple resources.
+// Fails when any value is not available (or is requested more than once).
+func SetValues(name mesos.ResourceName, values []string, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
 	res := mesos.Resources(in).Clone()
 
-	f := filter.And(filter.Name(name), filter.Range())
+	f := filter.And(filter.Name(name), filter.Set())
 
-	ranges := filter.All(f, res...)
+	sets := filter.All(f, res...)
 	rem := filter.All(filter.Not(f), res...)
 
-	// sort values
-
-	toFind := append(uints64{}, values...)
-	sort.Sort(toFind)
-	takeRanges := mesos.Resources{}
-
-	for i, res := range ranges {
-		take := mesos.Ranges{}
-		skip := mesos.Ranges{}
+	toFind := make(map[string]bool)
+	for _, v := range values {
+		if toFind[v] {
+			// duplicate values
+			return nil, mesos.Resources(in), false
+		}
+		toFind[v] = true
+	}
 
-		toProcess := res.RangesOrZero()
-		toProcess.Sort()
+	take := mesos.Resources{}
 
-		for len(toProcess) > 0 {
-			if len(toFind) == 0 {
-				skip = append(skip, toProcess...)
-				break
-			}
+	for i, r := range sets {
+		if len(toFind) == 0 {
+			rem = append(rem, sets[i:]...)
+			break
+		}
 
-			// pop next
-			currentRange := toProcess[0]
-			toProcess = toProcess[1:]
-
-			for _, v := range toFind {
-				// split
-				l, r, ok := currentRange.Split(v)
-				if ok {
-					// prepend right range to process list
-					if r != nil {
-						toProcess = append(mesos.Ranges{r}, toProcess...)
-					}
-					// left range is appended to skip list
-					if l != nil {
-						skip = append(skip, l)
-					}
-					// create value range with single found element
-					take = append(take, &mesos.Value_Range{
-						Begin: mesos.UI64p(v),
-						End:   mesos.UI64p(v),
-					})
-					toFind = toFind[1:]
-					break
-				} else {
-					skip = append(skip, currentRange)
-				}
+		var found, left []string
+		for _, item := range r.GetSet().GetItem() {
+			if toFind[item] {
+				found = append(found, item)
+				delete(toFind, item)
+			} else {
+				left = append(left, item)
 			}
 		}
 
-		if len(take) > 0 {
-			// create clone with ranges to use
-			cl := res.Clone()
-			cl.Ranges = &mesos.Value_Ranges{Range: take}
-			takeRanges = append(takeRanges, cl)
+		if len(found) == 0 {
+			rem = append(rem, r)
+			continue
 		}
 
-		if len(skip) > 0 {
-			// create clone with ranges to skip
-			cl := res.Clone()
-			cl.Ranges = &mesos.Value_Ranges{Range: skip}
-			rem = append(rem, cl)
-		}
+		cl := r.Clone()
+		cl.Set = &mesos.Value_Set{Item: found}
+		take = append(take, cl)
 
-		if len(toFind) == 0 {
-			// append all other
-			rem = append(rem, ranges[i+1:]...)
-			break
+		if len(left) > 0 {
+			cl := r.Clone()
+			cl.Set = &mesos.Value_Set{Item: left}
+			rem = append(rem, cl)
 		}
 	}
 
 	if len(toFind) == 0 {
-		return takeRanges, rem, true
+		return take, rem, true
 	} else {
 		return nil, mesos.Resources(in), false
 	}
 }
-
-type uints64 []uint64
-
-func (u uints64) Len() int      { return len(u) }
-func (r uints64) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
-func (r uints64) Less(i, j int) bool {
-	return r[i] < r[j]
-}
@@ -29,6 +29,14 @@ var _ = Describe("Find", func() {
 				Cpus(0.5).WithRole("my_role"),
 				mesos.Resources{Cpus(0.5).WithRole("my_role"), Cpus(1), Mem(256)},
 			},
+			{
+				mesos.CPUS,
+				mesos.Resources{Cpus(1).WithAllocationRole("a"), Mem(256).WithAllocationRole("a")},
+				0.25,
+				true,
+				Cpus(0.25).WithAllocationRole("a"),
+				mesos.Resources{Cpus(0.75).WithAllocationRole("a"), Mem(256).WithAllocationRole("a")},
+			},
 			{
 				mesos.CPUS,
 				mesos.Resources{Cpus(1), Cpus(1), Mem(256)},
@@ -64,23 +72,32 @@ var _ = Describe("Find", func() {
 		}
 	})
 
+	It("AllocatedTo", func() {
+		in := mesos.Resources{Cpus(1).WithAllocationRole("a"), Cpus(2).WithAllocationRole("b"), Mem(256).WithAllocationRole("a")}
+
+		allocated, rem := AllocatedTo("a", in...)
+		Expect(allocated).To(Equal(mesos.Resources{Cpus(1).WithAllocationRole("a"), Mem(256).WithAllocationRole("a")}))
+		Expect(rem).To(Equal(mesos.Resources{Cpus(2).WithAllocationRole("b")}))
+
+		allocated, rem = AllocatedTo("c", in...)
+		Expect(allocated).To(BeEmpty())
+		Expect(rem).To(Equal(in))
+	})
+
 	It("RandomInRange", func() {
 
 		var tests = []struct {
 			name       mesos.ResourceName
 			in         mesos.Resources
 			shouldFind bool
-			find       *mesos.Resource
-			rem        mesos.Resources
-			randFunc   func(i int) int
+			// first non-empty ranges resource, found value must be taken from it
+			from *mesos.Resource
 		}{
 			{
 				mesos.PORTS,
 				mesos.Resources{Ports(mesos.NewRange(2, 3)).WithRole("my_role"), Cpus(1), Mem(256)},
 				true,
-				Ports(mesos.NewRange(3, 3)).WithRole("my_role"),
-				mesos.Resources{Ports(mesos.NewRange(2, 2)).WithRole("my_role"), Cpus(1), Mem(256)},
-				func(i int) int { return i - 1 },
+				Ports(mesos.NewRange(2, 3)).WithRole("my_role"),
 			},
 			{
 				mesos.PORTS,
@@ -88,12 +105,7 @@ var _ = Describe("Find", func() {
 					Ports(mesos.NewRange(1, 1), mesos.NewRange(2, 3), mesos.NewRange(4, 4), mesos.NewRange(5, 5)).WithRole("my_role"),
 					Ports(mesos.NewRange(5, 6))},
 				true,
-				Ports(mesos.NewRange(5, 5)).WithRole("my_role"),
-				mesos.Resources{
-					Ports(mesos.NewRange(1, 1), mesos.NewRange(2, 3), mesos.NewRange(4, 4)).WithRole("my_role"),
-					Ports(mesos.NewRange(5, 6)),
-				},
-				func(i int) int { return i - 1 },
+				Ports(mesos.NewRange(1, 5)).WithRole("my_role"),
 			},
 			{
 				mesos.PORTS,
@@ -101,38 +113,56 @@ var _ = Describe("Find", func() {
 					Ports(mesos.NewRange(1, 5), mesos.NewRange(6, 11), mesos.NewRange(12, 17)).WithRole("my_role"),
 				},
 				true,
-				Ports(mesos.NewRange(10, 10)).WithRole("my_role"),
+				Ports(mesos.NewRange(1, 17)).WithRole("my_role"),
+			},
+			{
+				mesos.PORTS,
 				mesos.Resources{
-					Ports(mesos.NewRange(1, 5), mesos.NewRange(6, 9), mesos.NewRange(11, 11), mesos.NewRange(12, 17)).WithRole("my_role"),
+					Ports(mesos.NewRange(10, 12), mesos.NewRange(1, 2)).WithRole("my_role"),
 				},
-				func(i int) int { return i - 2 },
+				true,
+				Ports(mesos.NewRange(1, 2), mesos.NewRange(10, 12)).WithRole("my_role"),
 			},
 			{
 				mesos.PORTS,
 				mesos.Resources{Cpus(1), Mem(256)},
 				false,
 				nil,
-				mesos.Resources{Cpus(1), Mem(256)},
-				func(i int) int { return i - 1 },
 			},
 		}
 
 		for i, tt := range tests {
-			// overwrite rand func
-			RandFunc = tt.randFunc
+			var seen mesos.Ranges
 
-			found, rem, ok := RandomInRange(tt.name, tt.in...)
-			Expect(ok).To(Equal(tt.shouldFind),
-				fmt.Sprintf("[%v] RandomInRange[%v]: in %v: should found '%v' (expected '%v')",
-					i, tt.name, tt.in, ok, tt.shouldFind))
+			for j := 0; j < 1000; j++ {
+				found, rem, ok := RandomInRange(tt.name, tt.in...)
+				Expect(ok).To(Equal(tt.shouldFind),
+					fmt.Sprintf("[%v] RandomInRange[%v]: in %v: should found '%v' (expected '%v')",
+						i, tt.name, tt.in, ok, tt.shouldFind))
 
-			Expect(tt.find).To(Equal(found),
-				fmt.Sprintf("[%v] RandomInRange[%v]: in %v: found %v  (expected  %v)",
-					i, tt.name, tt.in, found, tt.find))
+				if !tt.shouldFind {
+					Expect(found).To(BeNil())
+					Expect(rem).To(ConsistOf(tt.in))
+					break
+				}
 
-			Expect(rem).To(ConsistOf(tt.rem),
-				fmt.Sprintf("[%v] RandomInRange[%v]: in %v, rem %v (expected %v)",
-					i, tt.name, tt.in, rem, tt.rem))
+				Expect(found.RangesOrZero().Size()).To(Equal(uint64(1)),
+					fmt.Sprintf("[%v] RandomInRange[%v]: in %v: found %v", i, tt.name, tt.in, found))
+				Expect(mesos.Resources{tt.from}.Contains(found)).To(BeTrue(),
+					fmt.Sprintf("[%v] RandomInRange[%v]: in %v: found %v (expected value of %v)",
+						i, tt.name, tt.in, found, tt.from))
+				Expect(rem.Add(found).Equals(tt.in)).To(BeTrue(),
+					fmt.Sprintf("[%v] RandomInRange[%v]: in %v: found %v, rem %v",
+						i, tt.name, tt.in, found, rem))
+
+				seen = seen.Union(found.RangesOrZero())
+			}
+
+			if tt.shouldFind {
+				// every value of first non-empty ranges resource can be selected
+				Expect(seen.Normalize().String()).To(Equal(tt.from.RangesOrZero().Normalize().String()),
+					fmt.Sprintf("[%v] RandomInRange[%v]: in %v: selected values %v", i, tt.name, tt.in, seen))
+			}
 		}
 	})
 
@@ -177,6 +207,14 @@ var _ = Describe("Find", func() {
 					Ports(mesos.NewRange(18, 19)).WithRole("my_role"),
 				},
 			},
+			{
+				mesos.PORTS,
+				mesos.Resources{Ports(mesos.NewRange(2, 9))},
+				[]uint64{3, 3},
+				false,
+				nil,
+				mesos.Resources{Ports(mesos.NewRange(2, 9))},
+			},
 			{
 				mesos.PORTS,
 				mesos.Resources{Cpus(1)},
@@ -211,4 +249,122 @@ var _ = Describe("Find", func() {
 		}
 
 	})
+
+	It("SetItems", func() {
+		gpus := func(items ...string) *mesos.Resource {
+			return NewSet("devices", items...)
+		}
+
+		var tests = []struct {
+			in         mesos.Resources
+			n          int
+			shouldFind bool
+			find       mesos.Resources
+			rem        mesos.Resources
+		}{
+			{
+				mesos.Resources{gpus("a", "b", "c"), Cpus(1)},
+				2,
+				true,
+				mesos.Resources{gpus("a", "b")},
+				mesos.Resources{Cpus(1), gpus("c")},
+			},
+			{
+				mesos.Resources{gpus("a").WithRole("r"), gpus(), gpus("b", "c")},
+				2,
+				true,
+				mesos.Resources{gpus("a").WithRole("r"), gpus("b")},
+				mesos.Resources{gpus(), gpus("c")},
+			},
+			{
+				mesos.Resources{gpus("a", "b"), gpus("c")},
+				2,
+				true,
+				mesos.Resources{gpus("a", "b")},
+				mesos.Resources{gpus("c")},
+			},
+			{
+				mesos.Resources{gpus("a"), gpus("b"), Cpus(1)},
+				3,
+				false,
+				nil,
+				mesos.Resources{gpus("a"), gpus("b"), Cpus(1)},
+			},
+			{
+				mesos.Resources{Cpus(1)},
+				0,
+				true,
+				mesos.Resources{},
+				mesos.Resources{Cpus(1)},
+			},
+		}
+
+		for i, tt := range tests {
+			in := tt.in.Clone()
+			found, rem, ok := SetItems("devices", tt.n, in...)
+			Expect(ok).To(Equal(tt.shouldFind), fmt.Sprintf("[%v] SetItems %v in %v", i, tt.n, tt.in))
+			Expect(found).To(Equal(tt.find), fmt.Sprintf("[%v] SetItems %v in %v: found %v", i, tt.n, tt.in, found))
+			Expect(rem).To(ConsistOf(tt.rem), fmt.Sprintf("[%v] SetItems %v in %v: rem %v", i, tt.n, tt.in, rem))
+			Expect(in).To(Equal(tt.in), fmt.Sprintf("[%v] SetItems %v in %v: input modified", i, tt.n, tt.in))
+		}
+	})
+
+	It("SetValues", func() {
+		gpus := func(items ...string) *mesos.Resource {
+			return NewSet("devices", items...)
+		}
+
+		var tests = []struct {
+			in         mesos.Resources
+			values     []string
+			shouldFind bool
+			find       mesos.Resources
+			rem        mesos.Resources
+		}{
+			{
+				mesos.Resources{gpus("a", "b", "c"), Cpus(1)},
+				[]string{"c", "a"},
+				true,
+				mesos.Resources{gpus("a", "c")},
+				mesos.Resources{Cpus(1), gpus("b")},
+			},
+			{
+				mesos.Resources{gpus("a").WithRole("r"), gpus("b", "c")},
+				[]string{"a", "b", "c"},
+				true,
+				mesos.Resources{gpus("a").WithRole("r"), gpus("b", "c")},
+				mesos.Resources{},
+			},
+			{
+				mesos.Resources{gpus("x"), gpus("a")},
+				[]string{"a"},
+				true,
+				mesos.Resources{gpus("a")},
+				mesos.Resources{gpus("x")},
+			},
+			{
+				mesos.Resources{gpus("a", "b")},
+				[]string{"a", "d"},
+				false,
+				nil,
+				mesos.Resources{gpus("a", "b")},
+			},
+			{
+				mesos.Resources{gpus("a", "b")},
+				[]string{"a", "a"},
+				false,
+				nil,
+				mesos.Resources{gpus("a", "b")},
+			},
+		}
+
+		for i, tt := range tests {
+			in := tt.in.Clone()
+			found, rem, ok := SetValues("devices", tt.values, in...)
+			Expect(ok).To(Equal(tt.shouldFind), fmt.Sprintf("[%v] SetValues %v in %v", i, tt.values, tt.in))
+			Expect(found).To(Equal(tt.find), fmt.Sprintf("[%v] SetValues %v in %v: found %v", i, tt.values, tt.in, found))
+			Expect(rem).To(ConsistOf(tt.rem), fmt.Sprintf("[%v] SetValues %v in %v: rem %v", i, tt.values, tt.in, rem))
+			Expect(in).To(Equal(tt.in), fmt.Sprintf("[%v] SetValues %v in %v: input modified", i, tt.values, tt.in))
+		}
+	})
 })
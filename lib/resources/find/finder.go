@@ -0,0 +1,222 @@
+package find
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
+)
+
+type (
+	// Controls which values are selected from ranges resources (ports)
+	PortStrategy int
+
+	Opt func(f *Finder)
+
+	// Finds resources using configured port strategy and random source, safe for concurrent use.
+	// Finder with non-random strategy or seeded source always selects the same values for the same input.
+	Finder struct {
+		strategy PortStrategy
+		rnd      *rand.Rand
+		sync.Mutex
+	}
+)
+
+const (
+	// Values are selected uniformly at random
+	RANDOM = PortStrategy(iota)
+	// Lowest values are selected
+	LOWEST_FIRST
+	// Highest values are selected
+	HIGHEST_FIRST
+)
+
+func (s PortStrategy) String() string {
+	switch s {
+	case RANDOM:
+		return "RANDOM"
+	case LOWEST_FIRST:
+		return "LOWEST_FIRST"
+	case HIGHEST_FIRST:
+		return "HIGHEST_FIRST"
+	default:
+		return fmt.Sprintf("PortStrategy(%v)", int(s))
+	}
+}
+
+func WithPortStrategy(s PortStrategy) Opt {
+	return func(f *Finder) {
+		f.strategy = s
+	}
+}
+
+// Source of randomness for RANDOM strategy (global math/rand source is used by default)
+func WithSource(src rand.Source) Opt {
+	return func(f *Finder) {
+		f.rnd = rand.New(src)
+	}
+}
+
+// Same as WithSource(rand.NewSource(seed))
+func WithSeed(seed int64) Opt {
+	return WithSource(rand.NewSource(seed))
+}
+
+// Default strategy is RANDOM
+func New(opts ...Opt) *Finder {
+	f := &Finder{}
+
+	for _, o := range opts {
+		o(f)
+	}
+
+	return f
+}
+
+// Selects value of first non-empty ranges resource
+func (f *Finder) InRange(name mesos.ResourceName, in ...*mesos.Resource) (*mesos.Resource, mesos.Resources, bool) {
+	allResources := mesos.Resources(in).Clone()
+	rf := filter.And(filter.Name(name), filter.Range())
+
+	ranges := filter.All(rf, allResources...)
+	others := filter.All(filter.Not(rf), allResources...)
+
+	for i, r := range ranges {
+		resRanges := r.RangesOrZero().Normalize()
+		if resRanges.Size() == 0 {
+			others = append(others, ranges[i])
+			continue
+		}
+
+		selected := f.pick(resRanges, 1)
+
+		found := r.Clone()
+		found.Ranges = &mesos.Value_Ranges{Range: selected}
+
+		if rem := resRanges.Difference(selected); len(rem) > 0 {
+			notSelected := r.Clone()
+			notSelected.Ranges = &mesos.Value_Ranges{Range: rem}
+			others = append(others, notSelected)
+		}
+
+		// append all other resources
+		others = append(others, ranges[i+1:]...)
+
+		return found, others, true
+	}
+
+	return nil, mesos.Resources(in), false
+}
+
+// Selects n values from ranges resources of given name, values may be spread over multiple resources
+// (used in order of resources). Fails when there are less than n values available.
+func (f *Finder) InRanges(name mesos.ResourceName, n uint64, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	res := mesos.Resources(in).Clone()
+	rf := filter.And(filter.Name(name), filter.Range())
+
+	ranges := filter.All(rf, res...)
+	rem := filter.All(filter.Not(rf), res...)
+
+	take := mesos.Resources{}
+
+	for i, r := range ranges {
+		if n == 0 {
+			rem = append(rem, ranges[i:]...)
+			break
+		}
+
+		resRanges := r.RangesOrZero().Normalize()
+		selected := f.pick(resRanges, n)
+		if len(selected) == 0 {
+			rem = append(rem, r)
+			continue
+		}
+
+		cl := r.Clone()
+		cl.Ranges = &mesos.Value_Ranges{Range: selected}
+		take = append(take, cl)
+
+		if left := resRanges.Difference(selected); len(left) > 0 {
+			cl := r.Clone()
+			cl.Ranges = &mesos.Value_Ranges{Range: left}
+			rem = append(rem, cl)
+		}
+
+		n -= selected.Size()
+	}
+
+	if n == 0 {
+		return take, rem, true
+	} else {
+		return nil, mesos.Resources(in), false
+	}
+}
+
+// Same as InRanges(mesos.PORTS, n, in...)
+func (f *Finder) Ports(n uint64, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	return f.InRanges(mesos.PORTS, n, in...)
+}
+
+// Same as Request.Find but ports are selected using port strategy of finder
+func (f *Finder) Find(req Request, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	return req.find(f.pick, in...)
+}
+
+// Selects n values from normalized ranges (all values when ranges are smaller)
+func (f *Finder) pick(ranges mesos.Ranges, n uint64) mesos.Ranges {
+	size := ranges.Size()
+	if n == 0 {
+		return mesos.Ranges{}
+	}
+	if n >= size {
+		return ranges
+	}
+
+	switch f.strategy {
+	case LOWEST_FIRST:
+		return lowest(ranges, n)
+	case HIGHEST_FIRST:
+		first, _ := ranges.Nth(size - n)
+		return ranges.Intersection(mesos.Ranges{mesos.NewRange(first, ranges[len(ranges)-1].GetEnd())})
+	default:
+		picked := mesos.Ranges{}
+		left := ranges
+		for i := uint64(0); i < n; i++ {
+			v, _ := left.Nth(f.index(size - i))
+			picked = picked.Union(mesos.RangesOf(v))
+			left = left.Difference(mesos.RangesOf(v))
+		}
+		return picked
+	}
+}
+
+// Returns random number in [0,n)
+func (f *Finder) index(n uint64) uint64 {
+	if n > math.MaxInt64 {
+		n = math.MaxInt64
+	}
+
+	if f.rnd == nil {
+		return uint64(rand.Int63n(int64(n)))
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	return uint64(f.rnd.Int63n(int64(n)))
+}
+
+// Selects n lowest values from normalized ranges
+func lowest(ranges mesos.Ranges, n uint64) mesos.Ranges {
+	if n == 0 {
+		return mesos.Ranges{}
+	}
+	if n >= ranges.Size() {
+		return ranges
+	}
+
+	last, _ := ranges.Nth(n - 1)
+	return ranges.Intersection(mesos.Ranges{mesos.NewRange(ranges[0].GetBegin(), last)})
+}
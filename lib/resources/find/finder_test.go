@@ -0,0 +1,114 @@
+package find_test
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources/find"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Finder", func() {
+
+	It("Select ports using strategy", func() {
+		in := MustParse("ports(r):[1-3];cpus:1;ports:[10-12,20-20]")
+
+		var tests = []struct {
+			finder *Finder
+			n      uint64
+			find   string
+			rem    string
+		}{
+			{New(WithPortStrategy(LOWEST_FIRST)), 2, "ports(r):[1-2]", "cpus:1;ports(r):[3-3];ports:[10-12,20-20]"},
+			{New(WithPortStrategy(LOWEST_FIRST)), 5, "ports(r):[1-3];ports:[10-11]", "cpus:1;ports:[12-12,20-20]"},
+			{New(WithPortStrategy(HIGHEST_FIRST)), 2, "ports(r):[2-3]", "cpus:1;ports(r):[1-1];ports:[10-12,20-20]"},
+			{New(WithPortStrategy(HIGHEST_FIRST)), 5, "ports(r):[1-3];ports:[12-12,20-20]", "cpus:1;ports:[10-11]"},
+			{New(), 7, "ports(r):[1-3];ports:[10-12,20-20]", "cpus:1"},
+			{New(), 0, "", "cpus:1;ports(r):[1-3];ports:[10-12,20-20]"},
+		}
+
+		for i, tt := range tests {
+			found, rem, ok := tt.finder.Ports(tt.n, in...)
+			Expect(ok).To(BeTrue(), fmt.Sprintf("[%v] Ports %v in %v", i, tt.n, in))
+			Expect(found.String()).To(Equal(MustParse(tt.find).String()), fmt.Sprintf("[%v] Ports %v in %v", i, tt.n, in))
+			Expect(rem.String()).To(Equal(MustParse(tt.rem).String()), fmt.Sprintf("[%v] Ports %v in %v", i, tt.n, in))
+		}
+
+		found, rem, ok := New().Ports(8, in...)
+		Expect(ok).To(BeFalse())
+		Expect(found).To(BeNil())
+		Expect(rem).To(Equal(in))
+	})
+
+	It("Select random ports", func() {
+		in := MustParse("ports(r):[1-3];cpus:1;ports:[10-12,20-20]")
+		var seen mesos.Ranges
+
+		for seed := int64(0); seed < 100; seed++ {
+			found, rem, ok := New(WithSeed(seed)).Ports(2, in...)
+			Expect(ok).To(BeTrue())
+			Expect(found).To(HaveLen(1))
+			Expect(found[0].RangesOrZero().Size()).To(Equal(uint64(2)))
+			Expect(MustParse("ports(r):[1-3]").Contains(found...)).To(BeTrue(), fmt.Sprintf("seed %v: found %v", seed, found))
+			Expect(found.Add(rem...).Equals(in)).To(BeTrue())
+
+			seen = seen.Union(found[0].RangesOrZero())
+		}
+
+		// not bound to lowest or highest values
+		Expect(seen.Normalize().String()).To(Equal(mesos.Ranges{mesos.NewRange(1, 3)}.String()))
+	})
+
+	It("Same seed selects the same ports", func() {
+		in := MustParse("ports:[1-1000];ports(r):[2000-3000]")
+
+		a := New(WithSeed(42))
+		b := New(WithSeed(42))
+
+		for i := 0; i < 10; i++ {
+			fa, ra, _ := a.Ports(5, in...)
+			fb, rb, _ := b.Ports(5, in...)
+			Expect(fa).To(Equal(fb))
+			Expect(ra).To(Equal(rb))
+			Expect(fa.Add(ra...).Equals(in)).To(BeTrue())
+
+			pa, _, _ := a.InRange(mesos.PORTS, in...)
+			pb, _, _ := b.InRange(mesos.PORTS, in...)
+			Expect(pa).To(Equal(pb))
+		}
+	})
+
+	It("Find request using strategy", func() {
+		in := MustParse("cpus:2;ports:[10-20]")
+
+		found, rem, ok := New(WithPortStrategy(HIGHEST_FIRST)).Find(Request{Cpus: 1, Ports: 2}, in...)
+		Expect(ok).To(BeTrue())
+		Expect(found.String()).To(Equal("cpus(*):1;ports(*):[19-20]"))
+		Expect(rem.String()).To(Equal("cpus(*):1;ports(*):[10-18]"))
+	})
+
+	It("Concurrent use", func(done Done) {
+		f := New(WithSeed(1))
+		in := MustParse("ports:[1-100]")
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					found, _, ok := f.Ports(3, in...)
+					Expect(ok).To(BeTrue())
+					Expect(found[0].RangesOrZero().Size()).To(Equal(uint64(3)))
+				}
+			}()
+		}
+		wg.Wait()
+
+		close(done)
+	})
+})
@@ -0,0 +1,265 @@
+package find
+
+import (
+	"fmt"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
+)
+
+type (
+	// Controls which of reserved (role other than "*") and unreserved resources are used
+	ReservationPolicy int
+
+	// Declarative request for resources of single task, zero value of field means nothing is requested.
+	// Request is matched atomically - either all requested resources are found or none.
+	Request struct {
+		Cpus float64
+		Mem  float64
+		Disk float64
+		Gpus float64
+		// Number of ports (lowest available ports are taken, see Finder for other port strategies)
+		Ports int
+		// Number of items of SET resources by name
+		Sets map[mesos.ResourceName]int
+
+		// Reservation roles resources may come from, empty allows all roles (unreserved resources are
+		// always allowed unless excluded by policy)
+		Roles []string
+		// Revocable resources can be used (non-revocable are preferred)
+		Revocable bool
+		Policy    ReservationPolicy
+	}
+)
+
+// Resources that cannot be split between tasks
+var exclusive = filter.Or(filter.Shared(), filter.PersistentVolume(), filter.MountDisk(), filter.BlockDisk(), filter.RawDisk())
+
+const (
+	// Reserved resources are used first, unreserved only to cover the rest
+	PREFER_RESERVED = ReservationPolicy(iota)
+	// Unreserved resources are used first, reserved only to cover the rest
+	PREFER_UNRESERVED
+	// Only reserved resources are used
+	RESERVED_ONLY
+	// Only unreserved resources are used
+	UNRESERVED_ONLY
+)
+
+func (p ReservationPolicy) String() string {
+	switch p {
+	case PREFER_RESERVED:
+		return "PREFER_RESERVED"
+	case PREFER_UNRESERVED:
+		return "PREFER_UNRESERVED"
+	case RESERVED_ONLY:
+		return "RESERVED_ONLY"
+	case UNRESERVED_ONLY:
+		return "UNRESERVED_ONLY"
+	default:
+		return fmt.Sprintf("ReservationPolicy(%v)", int(p))
+	}
+}
+
+// Finds requested resources in given resources, requested amount may be spread over multiple resources
+// (e.g. reserved and unreserved cpus). Returns resources to be used in TaskInfo and remaining resources.
+// Persistent volumes, shared resources and MOUNT, BLOCK and RAW disks are never used to satisfy disk request.
+// When any part of request cannot be satisfied, nil and unmodified resources are returned.
+func (req Request) Find(in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	return req.find(lowest, in...)
+}
+
+// pick selects n values from normalized ranges
+func (req Request) find(pick func(mesos.Ranges, uint64) mesos.Ranges, in ...*mesos.Resource) (mesos.Resources, mesos.Resources, bool) {
+	pool := mesos.Resources(in).Clone()
+	found := mesos.Resources{}
+
+	scalars := []struct {
+		name  mesos.ResourceName
+		value float64
+	}{
+		{mesos.CPUS, req.Cpus},
+		{mesos.MEM, req.Mem},
+		{mesos.DISK, req.Disk},
+		{mesos.GPUS, req.Gpus},
+	}
+
+	for _, s := range scalars {
+		if s.value <= 0 {
+			continue
+		}
+		take, ok := req.takeScalar(pool, s.name, s.value)
+		if !ok {
+			return nil, mesos.Resources(in), false
+		}
+		found = append(found, take...)
+	}
+
+	if req.Ports > 0 {
+		take, ok := req.takeRanges(pool, mesos.PORTS, uint64(req.Ports), pick)
+		if !ok {
+			return nil, mesos.Resources(in), false
+		}
+		found = append(found, take...)
+	}
+
+	for name, n := range req.Sets {
+		if n <= 0 {
+			continue
+		}
+		take, ok := req.takeItems(pool, name, n)
+		if !ok {
+			return nil, mesos.Resources(in), false
+		}
+		found = append(found, take...)
+	}
+
+	rem := mesos.Resources{}
+	for _, r := range pool {
+		if !r.IsEmpty() {
+			rem = append(rem, r)
+		}
+	}
+
+	return mesos.Resources{}.Add(found...), rem, true
+}
+
+// Returns indexes of resources usable for request in order of preference
+func (req Request) candidates(pool mesos.Resources, name mesos.ResourceName, t mesos.Value_Type) []int {
+	var reserved, unreserved, revReserved, revUnreserved []int
+
+	for i, r := range pool {
+		if r.GetName() != string(name) || r.GetType() != t || r.IsEmpty() || !req.usable(r) {
+			continue
+		}
+
+		isReserved := r.GetRole() != mesos.Default_Resource_Role
+		switch {
+		case isReserved && r.Revocable == nil:
+			reserved = append(reserved, i)
+		case isReserved:
+			revReserved = append(revReserved, i)
+		case r.Revocable == nil:
+			unreserved = append(unreserved, i)
+		default:
+			revUnreserved = append(revUnreserved, i)
+		}
+	}
+
+	var res []int
+	switch req.Policy {
+	case PREFER_UNRESERVED:
+		res = append(append(append(append(res, unreserved...), reserved...), revUnreserved...), revReserved...)
+	case RESERVED_ONLY:
+		res = append(append(res, reserved...), revReserved...)
+	case UNRESERVED_ONLY:
+		res = append(append(res, unreserved...), revUnreserved...)
+	default:
+		res = append(append(append(append(res, reserved...), unreserved...), revReserved...), revUnreserved...)
+	}
+
+	return res
+}
+
+func (req Request) usable(r *mesos.Resource) bool {
+	if r.Revocable != nil && !req.Revocable {
+		return false
+	}
+
+	if exclusive.Accepts(r) {
+		return false
+	}
+
+	role := r.GetRole()
+	if role == mesos.Default_Resource_Role || len(req.Roles) == 0 {
+		return true
+	}
+
+	for _, allowed := range req.Roles {
+		if role == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Takes value from pool (modified in place)
+func (req Request) takeScalar(pool mesos.Resources, name mesos.ResourceName, value float64) (mesos.Resources, bool) {
+	found := mesos.Resources{}
+	need := value
+
+	for _, i := range req.candidates(pool, name, mesos.Value_SCALAR) {
+		if mesos.ToFixed64(need) <= 0 {
+			break
+		}
+
+		r := pool[i]
+		take := r.ScalarValueOrZero()
+		if mesos.ToFixed64(take) > mesos.ToFixed64(need) {
+			take = need
+		}
+
+		t := r.Clone()
+		t.Scalar.Value = mesos.F64p(take)
+		found = append(found, t)
+
+		r.Scalar.Value = mesos.F64p(mesos.SubtractFixed(r.ScalarValueOrZero(), take))
+		need = mesos.SubtractFixed(need, take)
+	}
+
+	return found, mesos.ToFixed64(need) <= 0
+}
+
+// Takes n values selected by pick from pool (modified in place)
+func (req Request) takeRanges(pool mesos.Resources, name mesos.ResourceName, n uint64, pick func(mesos.Ranges, uint64) mesos.Ranges) (mesos.Resources, bool) {
+	found := mesos.Resources{}
+
+	for _, i := range req.candidates(pool, name, mesos.Value_RANGES) {
+		if n == 0 {
+			break
+		}
+
+		r := pool[i]
+		ranges := r.RangesOrZero().Normalize()
+
+		take := pick(ranges, n)
+
+		t := r.Clone()
+		t.Ranges = &mesos.Value_Ranges{Range: take}
+		found = append(found, t)
+
+		r.Ranges = &mesos.Value_Ranges{Range: ranges.Difference(take)}
+		n -= take.Size()
+	}
+
+	return found, n == 0
+}
+
+// Takes n items from pool (modified in place)
+func (req Request) takeItems(pool mesos.Resources, name mesos.ResourceName, n int) (mesos.Resources, bool) {
+	found := mesos.Resources{}
+
+	for _, i := range req.candidates(pool, name, mesos.Value_SET) {
+		if n == 0 {
+			break
+		}
+
+		r := pool[i]
+		items := r.GetSet().GetItem()
+
+		k := n
+		if len(items) < k {
+			k = len(items)
+		}
+
+		t := r.Clone()
+		t.Set = &mesos.Value_Set{Item: append([]string{}, items[:k]...)}
+		found = append(found, t)
+
+		r.Set = &mesos.Value_Set{Item: append([]string{}, items[k:]...)}
+		n -= k
+	}
+
+	return found, n == 0
+}
@@ -0,0 +1,157 @@
+package find_test
+
+import (
+	"fmt"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources/find"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request", func() {
+
+	It("Find", func() {
+		var tests = []struct {
+			req        Request
+			in         string
+			shouldFind bool
+			find       string
+			rem        string
+		}{
+			{
+				Request{Cpus: 1, Mem: 128, Ports: 2},
+				"cpus:2;mem:256;ports:[31000-31001,31005-32000]",
+				true,
+				"cpus:1;mem:128;ports:[31000-31001]",
+				"cpus:1;mem:128;ports:[31005-32000]",
+			},
+			{
+				Request{Cpus: 2, Ports: 3},
+				"cpus:1;cpus(r):1.5;ports(r):[1-2];ports:[10-20]",
+				true,
+				"cpus(r):1.5;cpus:0.5;ports(r):[1-2];ports:[10-10]",
+				"cpus:0.5;ports:[11-20]",
+			},
+			{
+				Request{Cpus: 1, Policy: PREFER_UNRESERVED},
+				"cpus(r):1;cpus:2",
+				true,
+				"cpus:1",
+				"cpus(r):1;cpus:1",
+			},
+			{
+				Request{Cpus: 2, Policy: UNRESERVED_ONLY},
+				"cpus(r):1;cpus:1",
+				false,
+				"",
+				"cpus(r):1;cpus:1",
+			},
+			{
+				Request{Cpus: 1, Policy: RESERVED_ONLY},
+				"cpus(r):1;cpus:1",
+				true,
+				"cpus(r):1",
+				"cpus:1",
+			},
+			{
+				Request{Cpus: 1, Roles: []string{"a"}},
+				"cpus(b):1;cpus(a):0.5;cpus:1",
+				true,
+				"cpus(a):0.5;cpus:0.5",
+				"cpus(b):1;cpus:0.5",
+			},
+			{
+				// atomic - memory is missing
+				Request{Cpus: 1, Mem: 512},
+				"cpus:1;mem:256",
+				false,
+				"",
+				"cpus:1;mem:256",
+			},
+			{
+				Request{Disk: 100},
+				"disk(r, p)[id:/data]:1000;disk:150",
+				true,
+				"disk:100",
+				"disk(r, p)[id:/data]:1000;disk:50",
+			},
+			{
+				Request{Gpus: 1, Sets: map[mesos.ResourceName]int{"devices": 2}},
+				"gpus:2;devices:{a,b,c}",
+				true,
+				"gpus:1;devices:{a,b}",
+				"gpus:1;devices:{c}",
+			},
+			{
+				Request{Sets: map[mesos.ResourceName]int{"devices": 4}},
+				"devices:{a,b,c}",
+				false,
+				"",
+				"devices:{a,b,c}",
+			},
+			{
+				Request{},
+				"cpus:1",
+				true,
+				"",
+				"cpus:1",
+			},
+		}
+
+		for i, tt := range tests {
+			in := MustParse(tt.in)
+			found, rem, ok := tt.req.Find(in...)
+
+			Expect(ok).To(Equal(tt.shouldFind),
+				fmt.Sprintf("[%v] Find %+v in %v: found '%v' (expected '%v')", i, tt.req, in, ok, tt.shouldFind))
+
+			if tt.shouldFind {
+				Expect(found.String()).To(Equal(MustParse(tt.find).String()),
+					fmt.Sprintf("[%v] Find %+v in %v: found %v", i, tt.req, in, found))
+			} else {
+				Expect(found).To(BeNil())
+			}
+
+			Expect(rem.String()).To(Equal(MustParse(tt.rem).String()),
+				fmt.Sprintf("[%v] Find %+v in %v: rem %v", i, tt.req, in, rem))
+
+			// input is never modified
+			Expect(in.String()).To(Equal(MustParse(tt.in).String()))
+		}
+	})
+
+	It("Never split BLOCK and RAW disks", func() {
+		for _, typ := range []mesos.Resource_DiskInfo_Source_Type{filter.DISK_SOURCE_BLOCK, filter.DISK_SOURCE_RAW} {
+			disk := mesos.Resources{MustParse("disk:500")[0].Clone()}
+			disk[0].Disk = &mesos.Resource_DiskInfo{Source: &mesos.Resource_DiskInfo_Source{Type: typ.Enum()}}
+
+			found, rem, ok := Request{Disk: 100}.Find(disk...)
+			Expect(ok).To(BeFalse())
+			Expect(found).To(BeNil())
+			Expect(rem).To(Equal(disk))
+		}
+	})
+
+	It("Revocable resources", func() {
+		in := mesos.Resources{
+			Cpus(1).WithRevocable(&mesos.Resource_RevocableInfo{}),
+			Cpus(0.5),
+		}
+
+		_, _, ok := Request{Cpus: 1}.Find(in...)
+		Expect(ok).To(BeFalse())
+
+		found, rem, ok := Request{Cpus: 1, Revocable: true}.Find(in...)
+		Expect(ok).To(BeTrue())
+		Expect(found).To(HaveLen(2))
+		Expect(found[0].Revocable).To(BeNil())
+		Expect(found[0].ScalarValueOrZero()).To(Equal(0.5))
+		Expect(found[1].Revocable).NotTo(BeNil())
+		Expect(found[1].ScalarValueOrZero()).To(Equal(0.5))
+		Expect(rem).To(HaveLen(1))
+		Expect(rem[0].Revocable).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,37 @@
+package find
+
+import (
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/filter"
+)
+
+// Finds persistent volume by persistence id, volume is always taken as a whole
+func PersistentVolume(id string, in ...*mesos.Resource) (*mesos.Resource, mesos.Resources, bool) {
+	res := mesos.Resources(in).Clone()
+
+	vol, rem := filter.First(filter.PersistentVolumeId(id), res...)
+
+	if vol == nil {
+		return nil, mesos.Resources(in), false
+	}
+
+	return vol, rem, true
+}
+
+// Splits resources to those reserved by reservation (role, principal and labels match) and all others,
+// used to find resources reserved by framework in later offers
+func Reserved(r resources.Reservation, in ...*mesos.Resource) (mesos.Resources, mesos.Resources) {
+	res := mesos.Resources(in).Clone()
+	f := filter.FilterFunc(r.Matches)
+
+	return filter.All(f, res...), filter.All(filter.Not(f), res...)
+}
+
+// Splits resources to those with reservation label and all others
+func Labeled(key, value string, in ...*mesos.Resource) (mesos.Resources, mesos.Resources) {
+	res := mesos.Resources(in).Clone()
+	f := filter.ReservationLabel(key, value)
+
+	return filter.All(f, res...), filter.All(filter.Not(f), res...)
+}
@@ -0,0 +1,244 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	// generated Mesos messages are registered in golang protobuf registry (required to resolve enum names)
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/pkg/errors"
+)
+
+// Parses resources in text format used by agent --resources flag and produced by mesos.Resources.String
+//
+//	cpus(role):2;mem:1024;ports:[31000-32000];gpus:{a,b};disk(role, principal)[id:/container/path]:100
+//
+// Every resource is name, optional "(allocated: role)", optional "(role)" or "(role, principal)",
+// optional disk info "[persistence id:host path:container path:ro|rw]" (host path and mode are optional)
+// and value after ':' (scalar, [ranges] or {set}). Role defaults to "*".
+// Every parsed resource must be valid (see mesos.Resource.Validate).
+// Reservation labels and disk sources cannot be expressed in text format.
+//
+// Text starting with '[' is parsed as JSON array of resources (as accepted by agent).
+func Parse(text string) (mesos.Resources, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "[") {
+		return parseJson(text)
+	}
+
+	res := mesos.Resources{}
+	for i, entry := range strings.Split(text, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		r, err := parseResource(entry)
+		if err == nil {
+			err = r.Validate()
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Parse: resource %v %q", i+1, entry)
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// Same as Parse but panics on error, intended for tests and static configuration
+func MustParse(text string) mesos.Resources {
+	res, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func parseJson(text string) (mesos.Resources, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, errors.Wrap(err, "Parse: invalid JSON")
+	}
+
+	res := make(mesos.Resources, len(raw))
+	for i, r := range raw {
+		res[i] = &mesos.Resource{}
+		if err := jsonpb.Unmarshal(bytes.NewReader(r), res[i]); err != nil {
+			return nil, errors.Wrapf(err, "Parse: resource %v", i+1)
+		}
+		if err := res[i].Validate(); err != nil {
+			return nil, errors.Wrapf(err, "Parse: resource %v", i+1)
+		}
+	}
+
+	return res, nil
+}
+
+func parseResource(entry string) (*mesos.Resource, error) {
+	end := strings.IndexAny(entry, "([:")
+	if end < 0 {
+		return nil, errors.New("missing ':' between name and value")
+	}
+
+	name := strings.TrimSpace(entry[:end])
+	if name == "" {
+		return nil, errors.New("empty name")
+	}
+
+	r := &mesos.Resource{Name: mesos.Strp(name), Role: mesos.Strp(mesos.Default_Resource_Role)}
+	rest := entry[end:]
+
+	// at most one "(allocated: role)" followed by at most one "(role[, principal])"
+	allocated, reserved := false, false
+	for strings.HasPrefix(rest, "(") {
+		closing := strings.Index(rest, ")")
+		if closing < 0 {
+			return nil, errors.New("missing ')'")
+		}
+
+		spec := strings.TrimSpace(rest[1:closing])
+		var err error
+		switch {
+		case strings.HasPrefix(spec, "allocated:") && allocated:
+			return nil, errors.New("allocation role set more than once")
+		case strings.HasPrefix(spec, "allocated:") && reserved:
+			return nil, errors.New("allocation role must precede role")
+		case strings.HasPrefix(spec, "allocated:"):
+			allocated = true
+			err = parseAllocationRole(r, spec)
+		case reserved:
+			return nil, errors.New("role set more than once")
+		default:
+			reserved = true
+			err = parseRole(r, spec)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		rest = strings.TrimSpace(rest[closing+1:])
+	}
+
+	if strings.HasPrefix(rest, "[") {
+		closing := strings.Index(rest, "]")
+		if closing < 0 {
+			return nil, errors.New("missing ']' after disk info")
+		}
+		r.Disk = parseDisk(rest[1:closing])
+		rest = strings.TrimSpace(rest[closing+1:])
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return nil, errors.Errorf("expected ':' before value, got %q", rest)
+	}
+
+	return r, parseValue(r, strings.TrimSpace(rest[1:]))
+}
+
+func parseAllocationRole(r *mesos.Resource, spec string) error {
+	role := strings.TrimSpace(strings.TrimPrefix(spec, "allocated:"))
+	if role == "" {
+		return errors.New("empty allocation role")
+	}
+	r.WithAllocationRole(role)
+	return nil
+}
+
+func parseRole(r *mesos.Resource, spec string) error {
+	parts := strings.SplitN(spec, ",", 2)
+	role := strings.TrimSpace(parts[0])
+	if role == "" {
+		return errors.New("empty role")
+	}
+	r.Role = mesos.Strp(role)
+
+	if len(parts) == 2 {
+		p := strings.TrimSpace(parts[1])
+		if p == "" {
+			return errors.New("empty principal")
+		}
+		r.Reservation = &mesos.Resource_ReservationInfo{Principal: mesos.Strp(p)}
+	}
+
+	return nil
+}
+
+func parseDisk(spec string) *mesos.Resource_DiskInfo {
+	d := &mesos.Resource_DiskInfo{}
+
+	parts := strings.Split(spec, ":")
+	if id := strings.TrimSpace(parts[0]); id != "" {
+		d.Persistence = &mesos.Resource_DiskInfo_Persistence{Id: mesos.Strp(id)}
+	}
+
+	vconfig := parts[1:]
+	if len(vconfig) == 0 {
+		return d
+	}
+
+	v := &mesos.Volume{}
+	if last := vconfig[len(vconfig)-1]; len(vconfig) > 1 && (last == "ro" || last == "rw") {
+		if last == "ro" {
+			v.Mode = mesos.Volume_RO.Enum()
+		} else {
+			v.Mode = mesos.Volume_RW.Enum()
+		}
+		vconfig = vconfig[:len(vconfig)-1]
+	}
+
+	if len(vconfig) > 1 {
+		v.HostPath = mesos.Strp(vconfig[0])
+		vconfig = vconfig[1:]
+	}
+	v.ContainerPath = mesos.Strp(strings.Join(vconfig, ":"))
+	d.Volume = v
+
+	return d
+}
+
+func parseValue(r *mesos.Resource, value string) error {
+	switch {
+	case value == "":
+		return errors.New("empty value")
+	case strings.HasPrefix(value, "["):
+		ranges, err := mesos.ParseRanges(value)
+		if err != nil {
+			return err
+		}
+		r.Type = mesos.Value_RANGES.Enum()
+		r.Ranges = &mesos.Value_Ranges{Range: ranges}
+	case strings.HasPrefix(value, "{"):
+		if !strings.HasSuffix(value, "}") {
+			return errors.Errorf("set %q must be enclosed in {}", value)
+		}
+		body := strings.TrimSpace(value[1 : len(value)-1])
+		if strings.ContainsAny(body, "{}") {
+			return errors.Errorf("unexpected brace in set %q", value)
+		}
+		items := []string{}
+		if body != "" {
+			for _, i := range strings.Split(body, ",") {
+				item := strings.TrimSpace(i)
+				if item == "" {
+					return errors.Errorf("empty item in set %q", value)
+				}
+				items = append(items, item)
+			}
+		}
+		r.Type = mesos.Value_SET.Enum()
+		r.Set = &mesos.Value_Set{Item: items}
+	default:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.Errorf("scalar value %q is not a number", value)
+		}
+		r.Type = mesos.Value_SCALAR.Enum()
+		r.Scalar = &mesos.Value_Scalar{Value: mesos.F64p(v)}
+	}
+
+	return nil
+}
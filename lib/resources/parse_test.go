@@ -0,0 +1,121 @@
+package resources_test
+
+import (
+	"fmt"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+
+	It("Text format", func() {
+		res, err := Parse("cpus(role):2;mem:1024; ports:[31000-32000,33000];gpus:{a, b};disk(role)[persistence:/data]:100")
+		Expect(err).To(Succeed())
+		Expect(res).To(HaveLen(5))
+
+		Expect(res[0]).To(Equal(Cpus(2).WithRole("role")))
+		Expect(res[1]).To(Equal(Mem(1024).WithRole(mesos.Default_Resource_Role)))
+		Expect(res[2]).To(Equal(Ports(mesos.NewRange(31000, 32000), mesos.NewRange(33000, 33000)).WithRole(mesos.Default_Resource_Role)))
+		Expect(res[3].GetType()).To(Equal(mesos.Value_SET))
+		Expect(res[3].GetSet().GetItem()).To(Equal([]string{"a", "b"}))
+		Expect(res[4].GetDisk().GetPersistence().GetId()).To(Equal("persistence"))
+		Expect(res[4].GetDisk().GetVolume().GetContainerPath()).To(Equal("/data"))
+		Expect(res[4].ScalarValueOrZero()).To(Equal(100.0))
+	})
+
+	It("Reservation, allocation and volume", func() {
+		res, err := Parse("disk(allocated: a)(role, principal)[id:/host:/container:ro]:10")
+		Expect(err).To(Succeed())
+		Expect(res).To(HaveLen(1))
+
+		r := res[0]
+		Expect(r.AllocationRole()).To(Equal("a"))
+		Expect(r.GetRole()).To(Equal("role"))
+		Expect(r.GetReservation().GetPrincipal()).To(Equal("principal"))
+		Expect(r.GetDisk().GetPersistence().GetId()).To(Equal("id"))
+		Expect(r.GetDisk().GetVolume().GetHostPath()).To(Equal("/host"))
+		Expect(r.GetDisk().GetVolume().GetContainerPath()).To(Equal("/container"))
+		Expect(r.GetDisk().GetVolume().GetMode()).To(Equal(mesos.Volume_RO))
+	})
+
+	It("Round trip with String", func() {
+		var tests = []string{
+			"cpus(*):2.5",
+			"cpus(role):0.1;mem(*):1024",
+			"ports(*):[1-2,5-5,31000-32000]",
+			"gpus(*):{0,1}",
+			"cpus(allocated: a)(role, principal):1",
+			"disk(role, p)[id:/data]:100",
+			"disk(role, p)[id:/host:/data:rw]:100",
+			"disk(role)[:/data]:100",
+			"disk(role)[]:100",
+		}
+
+		for i, tt := range tests {
+			res, err := Parse(tt)
+			Expect(err).To(Succeed(), fmt.Sprintf("[%v] Parse %q", i, tt))
+			Expect(res.String()).To(Equal(tt), fmt.Sprintf("[%v] String of parsed %q", i, tt))
+		}
+
+		built := mesos.Resources{Cpus(1), Mem(64).WithRole("role"), Ports(mesos.NewRange(10, 20))}
+		Expect(MustParse(built.String()).Equals(built)).To(BeTrue())
+	})
+
+	It("Report errors", func() {
+		var tests = []struct {
+			in  string
+			err string
+		}{
+			{"cpus", "resource 1 \"cpus\": missing ':'"},
+			{"cpus:1;:2", "resource 2 \":2\": empty name"},
+			{"cpus:abc", "scalar value \"abc\" is not a number"},
+			{"cpus(role:1", "missing ')'"},
+			{"cpus():1", "empty role"},
+			{"cpus(allocated: ):1", "empty allocation role"},
+			{"disk[id:1", "missing ']'"},
+			{"cpus(role)x:1", "expected ':' before value"},
+			{"cpus:", "empty value"},
+			{"ports:[2-1]", "begin is greater than end"},
+			{"gpus:{a,b", "must be enclosed in {}"},
+			{"gpus:{a,,b}", "empty item"},
+			{`[{"name":"cpus","type":"NOPE"}]`, "resource 1"},
+			{`[{"name":`, "invalid JSON"},
+			{"cpus:NaN", "resource 1 \"cpus:NaN\": Invalid resource cpus: scalar value NaN is not a number"},
+			{"cpus:Inf", "scalar value +Inf is not a number"},
+			{"cpus:-1", "scalar value -1 < 0"},
+			{"disk(*)[id:/data]:1", "persistent volume cannot be created from unreserved resource"},
+			{`[{"name":"cpus","type":"SCALAR"}]`, "resource 1: Invalid resource cpus: scalar resource must have only scalar value"},
+			{"gpus:{a}}", "unexpected brace"},
+			{"gpus:{{a}", "unexpected brace"},
+			{"cpus(r)(q):1", "role set more than once"},
+			{"cpus(allocated: a)(allocated: b):1", "allocation role set more than once"},
+			{"cpus(r)(allocated: a):1", "allocation role must precede role"},
+			{"cpus(r, ):1", "empty principal"},
+		}
+
+		for i, tt := range tests {
+			_, err := Parse(tt.in)
+			Expect(err).To(HaveOccurred(), fmt.Sprintf("[%v] Parse %q", i, tt.in))
+			Expect(err.Error()).To(ContainSubstring(tt.err), fmt.Sprintf("[%v] Parse %q", i, tt.in))
+		}
+
+		Expect(func() { MustParse("cpus") }).To(Panic())
+	})
+
+	It("JSON format", func() {
+		res, err := Parse(`[
+			{"name": "cpus", "type": "SCALAR", "scalar": {"value": 2}, "role": "role"},
+			{"name": "ports", "type": "RANGES", "ranges": {"range": [{"begin": 31000, "end": 32000}]}},
+			{"name": "gpus", "type": "SET", "set": {"item": ["a"]}, "allocation_info": {"role": "a"}}
+		]`)
+		Expect(err).To(Succeed())
+		Expect(res.String()).To(Equal("cpus(role):2;ports(*):[31000-32000];gpus(allocated: a)(*):{a}"))
+
+		empty, err := Parse("[]")
+		Expect(err).To(Succeed())
+		Expect(empty).To(BeEmpty())
+	})
+})
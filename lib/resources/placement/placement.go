@@ -0,0 +1,308 @@
+package placement
+
+import (
+	"github.com/gogo/protobuf/proto"
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/constraint"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/find"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+type (
+	Opt func(c *Placer)
+
+	// Task to be placed, Info is used as template (agent id and resources are set on placement)
+	Task struct {
+		Info    *mesos.TaskInfo
+		Request find.Request
+		// Constraints evaluated against running and placed tasks of the same group (application)
+		Constraints constraint.Constraints
+		Group       string
+	}
+
+	// Offer being filled with tasks
+	Candidate struct {
+		Offer *mesos.Offer
+		// Resources not used by placed tasks
+		Remaining mesos.Resources
+		// Tasks placed on offer
+		Tasks []*mesos.TaskInfo
+
+		agent      string
+		attributes map[string]string
+		// shared counters of tasks placed on agent and attribute values of offer
+		agentTasks     *int
+		attributeTasks map[string]*int
+		offered        totals
+		left           totals
+	}
+
+	// Tasks placed so far by agent and offer attributes
+	State struct {
+		tasks      int
+		agents     map[string]*int
+		attributes map[attribute]*int
+	}
+
+	// Outcome of placement
+	Result struct {
+		// Offers with at least one placed task in order of offers
+		Accepted []*Candidate
+		// Offers without placed tasks
+		Unused []*mesos.Offer
+		// Tasks that did not fit any offer
+		Unplaced []*Task
+	}
+
+	// Places tasks on offers using strategy
+	Placer struct {
+		strategy Strategy
+		running  map[string][]constraint.Task
+	}
+
+	totals struct {
+		cpus, mem, disk, gpus float64
+		ports                 uint64
+	}
+
+	attribute struct {
+		name, value string
+	}
+)
+
+func WithStrategy(s Strategy) Opt {
+	return func(c *Placer) {
+		c.strategy = s
+	}
+}
+
+// Tasks of group already running, used to evaluate task constraints
+func WithRunning(group string, tasks ...constraint.Task) Opt {
+	return func(c *Placer) {
+		c.running[group] = append(c.running[group], tasks...)
+	}
+}
+
+// Default strategy is FirstFit
+func New(opts ...Opt) *Placer {
+	p := &Placer{strategy: FirstFit(), running: make(map[string][]constraint.Task)}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// Places tasks (in given order) on offers, every task is placed on single offer matching its request
+// and constraints. Neither offers nor tasks are modified.
+func (p *Placer) Place(offers []*mesos.Offer, tasks []*Task) *Result {
+	state := &State{agents: make(map[string]*int), attributes: make(map[attribute]*int)}
+
+	candidates := make([]*Candidate, len(offers))
+	for i, o := range offers {
+		candidates[i] = newCandidate(o, state)
+	}
+
+	_, firstFit := p.strategy.(firstFit)
+	res := &Result{}
+
+	groups := make(map[string]*constraint.Tracker)
+	for _, t := range tasks {
+		if _, ok := groups[t.Group]; !ok {
+			groups[t.Group] = constraint.NewTracker(p.running[t.Group]...)
+		}
+	}
+
+	tried := make([]bool, len(candidates))
+	for _, t := range tasks {
+		for i := range tried {
+			tried[i] = false
+		}
+		group := groups[t.Group]
+
+		for {
+			best := -1
+			for i, c := range candidates {
+				if tried[i] || !c.left.fits(t.Request) || !t.Constraints.Accepts(c.Offer, group) {
+					continue
+				}
+
+				if best < 0 || p.strategy.Better(t, c, candidates[best], state) {
+					best = i
+				}
+
+				if firstFit {
+					break
+				}
+			}
+
+			if best < 0 {
+				res.Unplaced = append(res.Unplaced, t)
+				break
+			}
+
+			c := candidates[best]
+			found, rem, ok := t.Request.Find(c.Remaining...)
+			if !ok {
+				// totals fit but role, revocable or reservation constraints do not
+				tried[best] = true
+				continue
+			}
+
+			info := proto.Clone(t.Info).(*mesos.TaskInfo)
+			info.AgentId = c.Offer.AgentId
+			info.Resources = found
+
+			c.Tasks = append(c.Tasks, info)
+			c.Remaining = rem
+			c.left = totalsOf(rem)
+			group.Add(constraint.TaskOn(c.Offer))
+			state.tasks++
+			*c.agentTasks++
+			for _, cnt := range c.attributeTasks {
+				*cnt++
+			}
+			break
+		}
+	}
+
+	for _, c := range candidates {
+		if len(c.Tasks) > 0 {
+			res.Accepted = append(res.Accepted, c)
+		} else {
+			res.Unused = append(res.Unused, c.Offer)
+		}
+	}
+
+	return res
+}
+
+// Returns accept call with launch operation of placed tasks
+func (c *Candidate) Accept() *scheduler.Call {
+	return scheduler.AcceptOffer(c.Offer.Id, scheduler.OpLaunch(c.Tasks...))
+}
+
+// Returns accept call for every offer with placed tasks
+func (r *Result) Accepts() []*scheduler.Call {
+	calls := make([]*scheduler.Call, len(r.Accepted))
+	for i, c := range r.Accepted {
+		calls[i] = c.Accept()
+	}
+	return calls
+}
+
+// Returns decline call for unused offers, nil when all offers were used
+func (r *Result) Decline() *scheduler.Call {
+	if len(r.Unused) == 0 {
+		return nil
+	}
+
+	ids := make([]*mesos.OfferID, len(r.Unused))
+	for i, o := range r.Unused {
+		ids[i] = o.Id
+	}
+	return scheduler.Decline(ids...)
+}
+
+// Returns number of placed tasks
+func (s *State) Tasks() int {
+	return s.tasks
+}
+
+// Returns number of tasks placed on agent
+func (s *State) AgentTasks(agentId string) int {
+	if cnt, ok := s.agents[agentId]; ok {
+		return *cnt
+	}
+	return 0
+}
+
+// Returns number of tasks placed on offers with attribute value
+func (s *State) AttributeTasks(name, value string) int {
+	if cnt, ok := s.attributes[attribute{name: name, value: value}]; ok {
+		return *cnt
+	}
+	return 0
+}
+
+func (s *State) agentCounter(agentId string) *int {
+	cnt, ok := s.agents[agentId]
+	if !ok {
+		cnt = new(int)
+		s.agents[agentId] = cnt
+	}
+	return cnt
+}
+
+func (s *State) attributeCounter(name, value string) *int {
+	key := attribute{name: name, value: value}
+	cnt, ok := s.attributes[key]
+	if !ok {
+		cnt = new(int)
+		s.attributes[key] = cnt
+	}
+	return cnt
+}
+
+// Returns agent id of offer
+func (c *Candidate) Agent() string {
+	return c.agent
+}
+
+// Returns value of offer attribute, empty when offer has no such attribute
+func (c *Candidate) Attribute(name string) string {
+	return c.attributes[name]
+}
+
+func newCandidate(o *mesos.Offer, s *State) *Candidate {
+	res := mesos.Resources(o.GetResources()).Clone()
+	t := totalsOf(res)
+
+	c := &Candidate{
+		Offer:          o,
+		Remaining:      res,
+		agent:          o.GetAgentId().GetValue(),
+		attributes:     make(map[string]string),
+		agentTasks:     s.agentCounter(o.GetAgentId().GetValue()),
+		attributeTasks: make(map[string]*int),
+		offered:        t,
+		left:           t,
+	}
+
+	for _, a := range o.GetAttributes() {
+		v := a.ValueString()
+		c.attributes[a.GetName()] = v
+		c.attributeTasks[a.GetName()] = s.attributeCounter(a.GetName(), v)
+	}
+
+	return c
+}
+
+// Sums values regardless of role and reservation, used to quickly skip offers that cannot fit request
+func totalsOf(res mesos.Resources) totals {
+	t := totals{}
+	for _, r := range res {
+		switch mesos.ResourceName(r.GetName()) {
+		case mesos.CPUS:
+			t.cpus += r.ScalarValueOrZero()
+		case mesos.MEM:
+			t.mem += r.ScalarValueOrZero()
+		case mesos.DISK:
+			t.disk += r.ScalarValueOrZero()
+		case mesos.GPUS:
+			t.gpus += r.ScalarValueOrZero()
+		case mesos.PORTS:
+			t.ports += r.RangesOrZero().Size()
+		}
+	}
+	return t
+}
+
+func (t totals) fits(req find.Request) bool {
+	return mesos.ToFixed64(t.cpus) >= mesos.ToFixed64(req.Cpus) &&
+		mesos.ToFixed64(t.mem) >= mesos.ToFixed64(req.Mem) &&
+		mesos.ToFixed64(t.disk) >= mesos.ToFixed64(req.Disk) &&
+		mesos.ToFixed64(t.gpus) >= mesos.ToFixed64(req.Gpus) &&
+		t.ports >= uint64(req.Ports)
+}
@@ -0,0 +1,203 @@
+package placement_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/constraint"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/find"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources/placement"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func offer(id, agent, res string, attrs ...string) *mesos.Offer {
+	o := &mesos.Offer{
+		Id:        &mesos.OfferID{Value: mesos.Strp(id)},
+		AgentId:   &mesos.AgentID{Value: mesos.Strp(agent)},
+		Hostname:  mesos.Strp(agent),
+		Resources: resources.MustParse(res),
+	}
+
+	for i := 0; i+1 < len(attrs); i += 2 {
+		o.Attributes = append(o.Attributes, &mesos.Attribute{
+			Name: mesos.Strp(attrs[i]),
+			Type: mesos.Value_TEXT.Enum(),
+			Text: &mesos.Value_Text{Value: mesos.Strp(attrs[i+1])},
+		})
+	}
+
+	return o
+}
+
+func tasks(n int, req find.Request) []*Task {
+	res := make([]*Task, n)
+	for i := range res {
+		id := "t" + strconv.Itoa(i+1)
+		res[i] = &Task{
+			Info: &mesos.TaskInfo{
+				Name:   mesos.Strp(id),
+				TaskId: &mesos.TaskID{Value: mesos.Strp(id)},
+			},
+			Request: req,
+		}
+	}
+	return res
+}
+
+// returns offer id of every placed task
+func placedOn(r *Result) map[string]string {
+	res := make(map[string]string)
+	for _, c := range r.Accepted {
+		for _, t := range c.Tasks {
+			res[t.TaskId.GetValue()] = c.Offer.Id.GetValue()
+		}
+	}
+	return res
+}
+
+var _ = Describe("Placement", func() {
+
+	It("First fit", func() {
+		offers := []*mesos.Offer{
+			offer("o1", "a1", "cpus:2;mem:256;ports:[1-10]"),
+			offer("o2", "a2", "cpus:2;mem:256;ports:[1-10]"),
+		}
+
+		res := New().Place(offers, tasks(3, find.Request{Cpus: 1, Mem: 128, Ports: 1}))
+		Expect(res.Unplaced).To(BeEmpty())
+		Expect(res.Unused).To(BeEmpty())
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o1", "t2": "o1", "t3": "o2"}))
+
+		t := res.Accepted[0].Tasks[1]
+		Expect(t.AgentId.GetValue()).To(Equal("a1"))
+		Expect(mesos.Resources(t.Resources).String()).To(Equal("cpus(*):1;mem(*):128;ports(*):[2-2]"))
+		Expect(res.Accepted[0].Remaining.String()).To(Equal("ports(*):[3-10]"))
+
+		calls := res.Accepts()
+		Expect(calls).To(HaveLen(2))
+		Expect(calls[1].GetType()).To(Equal(scheduler.Call_ACCEPT))
+		Expect(calls[1].Accept.OfferIds[0].GetValue()).To(Equal("o2"))
+		Expect(calls[1].Accept.Operations[0].Launch.TaskInfos).To(HaveLen(1))
+		Expect(res.Decline()).To(BeNil())
+	})
+
+	It("Best fit", func() {
+		offers := []*mesos.Offer{
+			offer("o1", "a1", "cpus:8;mem:1024"),
+			offer("o2", "a2", "cpus:1;mem:128"),
+			offer("o3", "a3", "cpus:2;mem:256"),
+		}
+
+		res := New(WithStrategy(BestFit())).Place(offers, tasks(3, find.Request{Cpus: 1, Mem: 128}))
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o2", "t2": "o3", "t3": "o3"}))
+		Expect(res.Unused).To(ConsistOf(offers[0]))
+		Expect(res.Decline().Decline.OfferIds[0].GetValue()).To(Equal("o1"))
+	})
+
+	It("Spread across agents and attributes", func() {
+		offers := []*mesos.Offer{
+			offer("o1", "a1", "cpus:4", "zone", "z1"),
+			offer("o2", "a1", "cpus:4", "zone", "z1"),
+			offer("o3", "a2", "cpus:4", "zone", "z1"),
+			offer("o4", "a3", "cpus:4", "zone", "z2"),
+			offer("o5", "a4", "cpus:4"),
+		}
+
+		res := New(WithStrategy(Spread())).Place(offers, tasks(4, find.Request{Cpus: 1}))
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o1", "t2": "o3", "t3": "o4", "t4": "o5"}))
+
+		res = New(WithStrategy(SpreadBy("zone"))).Place(offers, tasks(4, find.Request{Cpus: 1}))
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o1", "t2": "o4", "t3": "o1", "t4": "o4"}))
+
+		res = New(WithStrategy(Then(SpreadBy("zone"), Spread()))).Place(offers, tasks(4, find.Request{Cpus: 1}))
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o1", "t2": "o4", "t3": "o3", "t4": "o4"}))
+	})
+
+	It("Skip offers not matching request constraints", func() {
+		offers := []*mesos.Offer{
+			offer("o1", "a1", "cpus(other):4"),
+			offer("o2", "a2", "cpus(mine):4"),
+		}
+
+		res := New().Place(offers, tasks(2, find.Request{Cpus: 3, Roles: []string{"mine"}}))
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o2"}))
+		Expect(res.Unplaced).To(HaveLen(1))
+		Expect(res.Unplaced[0].Info.TaskId.GetValue()).To(Equal("t2"))
+		Expect(res.Unused).To(ConsistOf(offers[0]))
+
+		// offers are not modified
+		Expect(mesos.Resources(offers[1].Resources).String()).To(Equal("cpus(mine):4"))
+	})
+
+	It("Respect constraints", func() {
+		offers := []*mesos.Offer{
+			offer("o1", "a1", "cpus:4", "rack", "r1"),
+			offer("o2", "a2", "cpus:4", "rack", "r1"),
+			offer("o3", "a3", "cpus:4", "rack", "r2"),
+			offer("o4", "a4", "cpus:4", "rack", "r3"),
+		}
+
+		unique := constraint.Constraints{constraint.MustNew("rack", constraint.UNIQUE, "")}
+		ts := tasks(3, find.Request{Cpus: 1})
+		for _, t := range ts {
+			t.Constraints = unique
+			t.Group = "app"
+		}
+
+		p := New(WithRunning("app", constraint.TaskOn(offers[3])))
+		res := p.Place(offers, ts)
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o1", "t2": "o3"}))
+		Expect(res.Unplaced).To(HaveLen(1))
+
+		// tasks of other group are not counted
+		other := tasks(1, find.Request{Cpus: 1})[0]
+		other.Info.TaskId.Value = mesos.Strp("other")
+		other.Constraints = unique
+		res = p.Place(offers, []*Task{ts[0], other})
+		Expect(placedOn(res)).To(Equal(map[string]string{"t1": "o1", "other": "o1"}))
+	})
+})
+
+func benchmarkPlace(b *testing.B, s Strategy) {
+	offers := make([]*mesos.Offer, 5000)
+	for i := range offers {
+		agent := "a" + strconv.Itoa(i%1000)
+		offers[i] = offer(
+			"o"+strconv.Itoa(i),
+			agent,
+			fmt.Sprintf("cpus:%v;mem:%v;disk:10000;ports:[31000-32000]", 1+i%8, 1024*(1+i%4)),
+			"zone", "z"+strconv.Itoa(i%5),
+		)
+	}
+
+	ts := tasks(2000, find.Request{Cpus: 0.5, Mem: 256, Ports: 2})
+	p := New(WithStrategy(s))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if res := p.Place(offers, ts); len(res.Unplaced) > 0 {
+			b.Fatalf("unplaced tasks: %v", len(res.Unplaced))
+		}
+	}
+}
+
+func BenchmarkFirstFit(b *testing.B) {
+	benchmarkPlace(b, FirstFit())
+}
+
+func BenchmarkBestFit(b *testing.B) {
+	benchmarkPlace(b, BestFit())
+}
+
+func BenchmarkSpread(b *testing.B) {
+	benchmarkPlace(b, Spread())
+}
+
+func BenchmarkSpreadByAttribute(b *testing.B) {
+	benchmarkPlace(b, SpreadBy("zone"))
+}
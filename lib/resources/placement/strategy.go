@@ -0,0 +1,89 @@
+package placement
+
+type (
+	// Selects offer for task among offers task fits into
+	Strategy interface {
+		// Returns true when task should be placed on a rather than on b
+		Better(t *Task, a, b *Candidate, s *State) bool
+	}
+
+	StrategyFunc func(t *Task, a, b *Candidate, s *State) bool
+
+	firstFit struct{}
+)
+
+var _ = Strategy(StrategyFunc(nil))
+
+func (f StrategyFunc) Better(t *Task, a, b *Candidate, s *State) bool {
+	return f(t, a, b, s)
+}
+
+func (firstFit) Better(t *Task, a, b *Candidate, s *State) bool {
+	return false
+}
+
+// Places task on first offer (in order of offers) task fits into
+func FirstFit() Strategy {
+	return firstFit{}
+}
+
+// Places task on offer with least resources left after placement (relative to offered resources),
+// packing tasks on as few offers as possible
+func BestFit() Strategy {
+	return StrategyFunc(func(t *Task, a, b *Candidate, s *State) bool {
+		return a.leftover(t) < b.leftover(t)
+	})
+}
+
+// Places task on agent with least tasks placed, ties are resolved by order of offers
+func Spread() Strategy {
+	return StrategyFunc(func(t *Task, a, b *Candidate, s *State) bool {
+		return *a.agentTasks < *b.agentTasks
+	})
+}
+
+// Places task on offer whose attribute value has least tasks placed (e.g. spread across racks or zones),
+// offers without attribute are used only when no offer with attribute fits task
+func SpreadBy(attribute string) Strategy {
+	return StrategyFunc(func(t *Task, a, b *Candidate, s *State) bool {
+		ac, aok := a.attributeTasks[attribute]
+		bc, bok := b.attributeTasks[attribute]
+		if !aok || !bok {
+			return aok
+		}
+		return *ac < *bc
+	})
+}
+
+// Combines strategies, following strategy is used only when previous ones consider offers equal
+func Then(strategies ...Strategy) Strategy {
+	return StrategyFunc(func(t *Task, a, b *Candidate, s *State) bool {
+		for _, st := range strategies {
+			if st.Better(t, a, b, s) {
+				return true
+			}
+			if st.Better(t, b, a, s) {
+				return false
+			}
+		}
+		return false
+	})
+}
+
+// Sum of fractions of offered scalar resources left after placing task
+func (c *Candidate) leftover(t *Task) float64 {
+	var sum float64
+
+	frac := func(left, offered, req float64) {
+		if offered > 0 {
+			sum += (left - req) / offered
+		}
+	}
+
+	frac(c.left.cpus, c.offered.cpus, t.Request.Cpus)
+	frac(c.left.mem, c.offered.mem, t.Request.Mem)
+	frac(c.left.disk, c.offered.disk, t.Request.Disk)
+	frac(c.left.gpus, c.offered.gpus, t.Request.Gpus)
+
+	return sum
+}
@@ -0,0 +1,116 @@
+package resources
+
+import (
+	"sort"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+)
+
+type (
+	// Dynamic reservation of resources for role
+	Reservation struct {
+		Role      string
+		Principal string
+		// Used to find reserved resources in later offers
+		Labels map[string]string
+	}
+
+	// Persistent volume created on reserved disk
+	Volume struct {
+		// Must be unique per role on agent
+		Id            string
+		ContainerPath string
+		ReadOnly      bool
+		// Principal creating volume (usually the same as reservation principal)
+		Principal string
+	}
+)
+
+// Returns reservation info with principal and labels sorted by key
+func (r Reservation) Info() *mesos.Resource_ReservationInfo {
+	ri := &mesos.Resource_ReservationInfo{}
+	if r.Principal != "" {
+		ri.Principal = mesos.Strp(r.Principal)
+	}
+	if len(r.Labels) > 0 {
+		ri.Labels = Labels(r.Labels)
+	}
+	return ri
+}
+
+// Returns copies of resources reserved for role, intended for unreserved resources of offer.
+// Returned resources are used both in RESERVE operation and in tasks launched after reservation.
+func (r Reservation) Reserve(res ...*mesos.Resource) mesos.Resources {
+	reserved := mesos.Resources(res).Clone()
+	for _, rs := range reserved {
+		rs.WithRole(r.Role).WithReservation(r.Info())
+	}
+	return reserved
+}
+
+// Returns true when resource is reserved for role by principal with all labels of reservation
+func (r Reservation) Matches(res *mesos.Resource) bool {
+	ri := res.GetReservation()
+	if ri == nil || res.GetRole() != r.Role || ri.GetPrincipal() != r.Principal {
+		return false
+	}
+
+	for k, v := range r.Labels {
+		if value, ok := Label(ri.GetLabels(), k); !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns persistent volume on copy of reserved disk resource,
+// used both in CREATE operation and in tasks using volume
+func (v Volume) Create(disk *mesos.Resource) *mesos.Resource {
+	mode := mesos.Volume_RW
+	if v.ReadOnly {
+		mode = mesos.Volume_RO
+	}
+
+	p := &mesos.Resource_DiskInfo_Persistence{Id: mesos.Strp(v.Id)}
+	if v.Principal != "" {
+		p.Principal = mesos.Strp(v.Principal)
+	}
+
+	vol := disk.Clone()
+	if vol.Disk == nil {
+		vol.Disk = &mesos.Resource_DiskInfo{}
+	}
+	vol.Disk.Persistence = p
+	vol.Disk.Volume = &mesos.Volume{
+		ContainerPath: mesos.Strp(v.ContainerPath),
+		Mode:          mode.Enum(),
+	}
+
+	return vol
+}
+
+// Returns labels sorted by key
+func Labels(kv map[string]string) *mesos.Labels {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	labels := &mesos.Labels{}
+	for _, k := range keys {
+		labels.Labels = append(labels.Labels, &mesos.Label{Key: mesos.Strp(k), Value: mesos.Strp(kv[k])})
+	}
+	return labels
+}
+
+// Returns value of label, false when there is no label with key
+func Label(labels *mesos.Labels, key string) (string, bool) {
+	for _, l := range labels.GetLabels() {
+		if l.GetKey() == key {
+			return l.GetValue(), true
+		}
+	}
+	return "", false
+}
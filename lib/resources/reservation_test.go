@@ -0,0 +1,81 @@
+package resources_test
+
+import (
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	. "github.com/ondrej-smola/mesos-go-http/lib/resources"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources/find"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reservation", func() {
+
+	reservation := Reservation{Role: "db", Principal: "fw", Labels: map[string]string{"app": "db", "instance": "1"}}
+	volume := Volume{Id: "db-1", ContainerPath: "data", Principal: "fw"}
+
+	It("Reserve resources and create volume", func() {
+		offered := MustParse("cpus:4;mem:1024;disk:1000")
+
+		reserved := reservation.Reserve(MustParse("cpus:1;mem:256;disk:100")...)
+		Expect(reserved.Validate()).To(Succeed())
+		Expect(reserved.String()).To(Equal("cpus(db, fw):1;mem(db, fw):256;disk(db, fw):100"))
+		Expect(reserved[0].Reservation.Labels.Labels).To(HaveLen(2))
+		Expect(reserved[0].Reservation.Labels.Labels[0].GetKey()).To(Equal("app"))
+		Expect(reservation.Matches(reserved[0])).To(BeTrue())
+		Expect(offered.Contains(reserved.Flatten(mesos.Default_Resource_Role, nil)...)).To(BeTrue())
+
+		vol := volume.Create(reserved[2])
+		Expect(vol.Validate()).To(Succeed())
+		Expect(mesos.Resources{vol}.String()).To(Equal("disk(db, fw)[db-1:data:rw]:100"))
+		Expect(vol.Disk.Persistence.GetPrincipal()).To(Equal("fw"))
+		// reserved disk is not modified
+		Expect(reserved[2].Disk).To(BeNil())
+
+		task := &mesos.TaskInfo{
+			Name:      mesos.Strp("db"),
+			TaskId:    &mesos.TaskID{Value: mesos.Strp("db-1")},
+			Resources: mesos.Resources{reserved[0], reserved[1], vol},
+		}
+
+		call := scheduler.ReserveCreateLaunch(&mesos.OfferID{Value: mesos.Strp("o1")}, reserved, mesos.Resources{vol}, task)
+		ops := call.Accept.Operations
+		Expect(ops).To(HaveLen(3))
+		Expect(ops[0].GetType()).To(Equal(mesos.Offer_Operation_RESERVE))
+		Expect(ops[0].Reserve.Resources).To(Equal([]*mesos.Resource(reserved)))
+		Expect(ops[1].GetType()).To(Equal(mesos.Offer_Operation_CREATE))
+		Expect(ops[1].Create.Volumes).To(Equal([]*mesos.Resource{vol}))
+		Expect(ops[2].GetType()).To(Equal(mesos.Offer_Operation_LAUNCH))
+
+		// nothing to reserve or create when relaunching on existing volume
+		call = scheduler.ReserveCreateLaunch(&mesos.OfferID{Value: mesos.Strp("o2")}, nil, nil, task)
+		Expect(call.Accept.Operations).To(HaveLen(1))
+		Expect(call.Accept.Operations[0].GetType()).To(Equal(mesos.Offer_Operation_LAUNCH))
+	})
+
+	It("Find reserved resources and volume in later offer", func() {
+		reserved := reservation.Reserve(MustParse("cpus:1;mem:256;disk:100")...)
+		other := Reservation{Role: "db", Principal: "fw", Labels: map[string]string{"app": "db", "instance": "2"}}
+		offered := append(mesos.Resources{
+			reserved[0],
+			reserved[1],
+			volume.Create(reserved[2]),
+		}, append(other.Reserve(Cpus(1)), Mem(512))...)
+
+		mine, rest := find.Reserved(reservation, offered...)
+		Expect(mine.String()).To(Equal("cpus(db, fw):1;mem(db, fw):256;disk(db, fw)[db-1:data:rw]:100"))
+		Expect(rest.String()).To(Equal("cpus(db, fw):1;mem(*):512"))
+
+		labeled, _ := find.Labeled("instance", "2", offered...)
+		Expect(labeled.String()).To(Equal("cpus(db, fw):1"))
+
+		vol, rem, ok := find.PersistentVolume("db-1", offered...)
+		Expect(ok).To(BeTrue())
+		Expect(vol.Disk.Volume.GetContainerPath()).To(Equal("data"))
+		Expect(rem).To(HaveLen(4))
+
+		_, rem, ok = find.PersistentVolume("db-2", offered...)
+		Expect(ok).To(BeFalse())
+		Expect(rem).To(Equal(offered))
+	})
+})
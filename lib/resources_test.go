@@ -0,0 +1,47 @@
+package mesos_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resources", func() {
+
+	cpus := func(v float64) *Resource {
+		return &Resource{Name: Strp(string(CPUS)), Type: Value_SCALAR.Enum(), Scalar: &Value_Scalar{Value: &v}}
+	}
+
+	It("Allocation role", func() {
+		r := cpus(1).WithRole("r").WithAllocationRole("a")
+		Expect(r.AllocationRole()).To(Equal("a"))
+		Expect(Resources{r}.String()).To(Equal("cpus(allocated: a)(r):1"))
+
+		Expect((&Offer{Resources: Resources{cpus(1)}}).AllocationRole()).To(BeEmpty())
+		Expect((&Offer{Resources: Resources{r}}).AllocationRole()).To(Equal("a"))
+		Expect((&Offer{AllocationInfo: &Resource_AllocationInfo{Role: Strp("b")}}).AllocationRole()).To(Equal("b"))
+
+		rs := Resources{cpus(1), cpus(2)}.WithAllocationRole("c")
+		Expect(rs[0].AllocationRole()).To(Equal("c"))
+		Expect(rs[1].AllocationRole()).To(Equal("c"))
+	})
+
+	It("Attribute value", func() {
+		o := &Offer{Attributes: []*Attribute{
+			{Name: Strp("zone"), Type: Value_TEXT.Enum(), Text: &Value_Text{Value: Strp("z1")}},
+			{Name: Strp("size"), Type: Value_SCALAR.Enum(), Scalar: &Value_Scalar{Value: F64p(2.5)}},
+			{Name: Strp("slots"), Type: Value_RANGES.Enum(), Ranges: &Value_Ranges{Range: Ranges{NewRange(1, 4)}}},
+			{Name: Strp("disks"), Type: Value_SET.Enum(), Set: &Value_Set{Item: []string{"a", "b"}}},
+		}}
+
+		for name, value := range map[string]string{"zone": "z1", "size": "2.5", "slots": "[1-4]", "disks": "{a,b}"} {
+			v, ok := o.AttributeValue(name)
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal(value))
+		}
+
+		_, ok := o.AttributeValue("rack")
+		Expect(ok).To(BeFalse())
+	})
+})
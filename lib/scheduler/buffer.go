@@ -0,0 +1,274 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+)
+
+type (
+	// Defines what happens when message cannot be buffered because buffer
+	// (or buffer for given message kind) is full
+	OverflowPolicy int
+
+	// Message buffer shared by event stream reader, loopback pushes and pulls.
+	// Message kind is its name (flow.Message.Name) e.g. Event_OFFERS.String() for offers.
+	buffer struct {
+		size      int
+		kindSizes map[string]int
+		policy    OverflowPolicy
+		onDrop    func(flow.Message)
+
+		msgs   []flow.Message
+		counts map[string]int
+		// number of waiting pull requests, message can be handed over to them even when buffer is full
+		waiting int
+		dropped map[string]uint64
+
+		// closed and replaced when message is buffered (pulls wait for it)
+		added chan struct{}
+		// closed and replaced when room is made by pop or waiting pull (blocked puts wait for it)
+		freed chan struct{}
+		sync.Mutex
+	}
+)
+
+const (
+	// Wait until there is space in buffer - reading from Mesos is paused and loopback push returns ErrBufferFull
+	OVERFLOW_BLOCK OverflowPolicy = iota
+	// Drop oldest buffered message of the same kind (or oldest buffered message when there is none)
+	OVERFLOW_DROP_OLDEST
+	// Drop incoming message, loopback push returns ErrBufferFull
+	OVERFLOW_DROP_NEWEST
+	// Merge incoming offers into already buffered offers event, all other messages are handled as OVERFLOW_BLOCK
+	OVERFLOW_COALESCE_OFFERS
+	// Close client with ErrBufferFull
+	OVERFLOW_FAIL
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OVERFLOW_BLOCK:
+		return "block"
+	case OVERFLOW_DROP_OLDEST:
+		return "drop_oldest"
+	case OVERFLOW_DROP_NEWEST:
+		return "drop_newest"
+	case OVERFLOW_COALESCE_OFFERS:
+		return "coalesce_offers"
+	case OVERFLOW_FAIL:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+func newBuffer(size int, kindSizes map[string]int, policy OverflowPolicy, onDrop func(flow.Message)) *buffer {
+	return &buffer{
+		size:      size,
+		kindSizes: kindSizes,
+		policy:    policy,
+		onDrop:    onDrop,
+		counts:    make(map[string]int),
+		dropped:   make(map[string]uint64),
+		added:     make(chan struct{}),
+		freed:     make(chan struct{}),
+	}
+}
+
+// Adds message to buffer, blocks (when policy requires it) until there is space or context is cancelled
+func (b *buffer) put(m flow.Message, ctx context.Context) error {
+	for {
+		ok, ch, err := b.add(m, true)
+		if ok || err != nil {
+			return err
+		}
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Adds message to buffer without blocking, returns ErrBufferFull when message could not be buffered
+func (b *buffer) offer(m flow.Message) error {
+	ok, _, err := b.add(m, false)
+	if err != nil {
+		return err
+	} else if !ok {
+		return ErrBufferFull
+	}
+	return nil
+}
+
+// Returns true when message was processed (buffered, merged or dropped) or channel to wait for next change
+func (b *buffer) add(m flow.Message, canWait bool) (bool, <-chan struct{}, error) {
+	var dropped flow.Message
+
+	b.Lock()
+	kind := m.Name()
+
+	if !b.hasRoom(kind) {
+		switch b.policy {
+		case OVERFLOW_FAIL:
+			b.Unlock()
+			return false, nil, ErrBufferFull
+		case OVERFLOW_DROP_NEWEST:
+			b.dropped[kind] += 1
+			b.Unlock()
+			b.drop(m)
+			if canWait {
+				return true, nil, nil
+			}
+			return false, nil, ErrBufferFull
+		case OVERFLOW_DROP_OLDEST:
+			dropped = b.removeOldest(kind)
+			if dropped == nil {
+				// nothing to drop, drop incoming message
+				dropped = m
+				b.dropped[kind] += 1
+				b.Unlock()
+				b.drop(dropped)
+				return canWait, nil, nil
+			}
+		case OVERFLOW_COALESCE_OFFERS:
+			if b.coalesce(m) {
+				b.Unlock()
+				return true, nil, nil
+			}
+			fallthrough
+		default:
+			ch := b.freed
+			b.Unlock()
+			return false, ch, nil
+		}
+	}
+
+	b.msgs = append(b.msgs, m)
+	b.counts[kind] += 1
+	b.added = signal(b.added)
+	b.Unlock()
+
+	if dropped != nil {
+		b.drop(dropped)
+	}
+
+	return true, nil, nil
+}
+
+// Removes and returns first message
+func (b *buffer) pop() (flow.Message, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(b.msgs) == 0 {
+		return nil, false
+	}
+
+	m := b.msgs[0]
+	b.msgs[0] = nil
+	b.msgs = b.msgs[1:]
+	b.counts[m.Name()] -= 1
+	b.freed = signal(b.freed)
+
+	return m, true
+}
+
+// Waits until message is buffered or any of contexts is cancelled, returns immediately when buffer is not empty
+func (b *buffer) wait(ctx, clientCtx context.Context) {
+	b.Lock()
+	if len(b.msgs) > 0 {
+		b.Unlock()
+		return
+	}
+	b.waiting += 1
+	// waiting pull makes room for handover, wake only blocked puts (not other pulls)
+	b.freed = signal(b.freed)
+	ch := b.added
+	b.Unlock()
+
+	select {
+	case <-ch:
+	case <-ctx.Done():
+	case <-clientCtx.Done():
+	}
+
+	b.Lock()
+	b.waiting -= 1
+	b.Unlock()
+}
+
+// Returns number of dropped messages by kind
+func (b *buffer) droppedCounts() map[string]uint64 {
+	b.Lock()
+	defer b.Unlock()
+
+	res := make(map[string]uint64, len(b.dropped))
+	for k, v := range b.dropped {
+		res[k] = v
+	}
+	return res
+}
+
+func (b *buffer) hasRoom(kind string) bool {
+	if limit, ok := b.kindSizes[kind]; ok && b.counts[kind] >= limit {
+		return false
+	}
+
+	return len(b.msgs) < b.size+b.waiting
+}
+
+func (b *buffer) removeOldest(kind string) flow.Message {
+	if len(b.msgs) == 0 {
+		return nil
+	}
+
+	idx := 0
+	for i, m := range b.msgs {
+		if m.Name() == kind {
+			idx = i
+			break
+		}
+	}
+
+	m := b.msgs[idx]
+	b.msgs = append(b.msgs[:idx], b.msgs[idx+1:]...)
+	b.counts[m.Name()] -= 1
+	b.dropped[m.Name()] += 1
+
+	return m
+}
+
+func (b *buffer) coalesce(m flow.Message) bool {
+	ok, offers := IsResourceOffers(m)
+	if !ok {
+		return false
+	}
+
+	for i := len(b.msgs) - 1; i >= 0; i-- {
+		if isBuffered, buffered := IsResourceOffers(b.msgs[i]); isBuffered {
+			if buffered.Offers == nil {
+				buffered.Offers = &Event_Offers{}
+			}
+			buffered.Offers.Offers = append(buffered.Offers.Offers, offers.GetOffers().GetOffers()...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *buffer) drop(m flow.Message) {
+	if b.onDrop != nil {
+		b.onDrop(m)
+	}
+}
+
+// Closes channel and returns its replacement, must be called with lock held
+func signal(ch chan struct{}) chan struct{} {
+	close(ch)
+	return make(chan struct{})
+}
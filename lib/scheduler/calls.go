@@ -104,6 +104,23 @@ func OpDestroy(rs ...*mesos.Resource) *mesos.Offer_Operation {
 	}
 }
 
+// Reserves resources, creates persistent volumes and launches tasks (in this order) in single accept call.
+// Operations without resources (or tasks) are omitted, tasks are expected to use reserved resources and volumes.
+func ReserveCreateLaunch(offerId *mesos.OfferID, reserve, volumes mesos.Resources, tasks ...*mesos.TaskInfo) *Call {
+	ops := []*mesos.Offer_Operation{}
+	if len(reserve) > 0 {
+		ops = append(ops, OpReserve(reserve...))
+	}
+	if len(volumes) > 0 {
+		ops = append(ops, OpCreate(volumes...))
+	}
+	if len(tasks) > 0 {
+		ops = append(ops, OpLaunch(tasks...))
+	}
+
+	return AcceptOffer(offerId, ops...)
+}
+
 func Revive(roles ...string) *Call {
 	return &Call{
 		Type: Call_REVIVE.Enum(),
@@ -158,6 +175,18 @@ func Shutdown(executorID, agentID string) *Call {
 	}
 }
 
+// Sends data to executor, Mesos does not guarantee delivery
+func Message(agentID, executorID string, data []byte) *Call {
+	return &Call{
+		Type: Call_MESSAGE.Enum(),
+		Message: &Call_Message{
+			AgentId:    &mesos.AgentID{Value: &agentID},
+			ExecutorId: &mesos.ExecutorID{Value: &executorID},
+			Data:       data,
+		},
+	}
+}
+
 func Acknowledge(agentID, taskID string, uuid []byte) *Call {
 	return &Call{
 		Type: Call_ACKNOWLEDGE.Enum(),
@@ -198,8 +227,7 @@ func Request(requests ...*mesos.Request) *Call {
 }
 
 func IsSubscribedMessage(e flow.Message) (bool, *Event) {
-	switch r := e.(type) {
-	case *Event:
+	if r, ok := EventOf(e); ok {
 		return IsSubscribed(r), r
 	}
 
@@ -207,14 +235,39 @@ func IsSubscribedMessage(e flow.Message) (bool, *Event) {
 }
 
 func IsResourceOffers(e flow.Message) (bool, *Event) {
-	switch r := e.(type) {
-	case *Event:
+	if r, ok := EventOf(e); ok {
 		return r.GetType() == Event_OFFERS, r
 	}
 
 	return false, nil
 }
 
+func IsRescind(e flow.Message) (bool, *Event) {
+	if r, ok := EventOf(e); ok {
+		return r.GetType() == Event_RESCIND, r
+	}
+
+	return false, nil
+}
+
+// Returns true for message sent by executor
+func IsMessage(e flow.Message) (bool, *Event) {
+	if r, ok := EventOf(e); ok {
+		return r.GetType() == Event_MESSAGE, r
+	}
+
+	return false, nil
+}
+
+func IsOfferAccept(e flow.Message) (bool, *Call) {
+	switch r := e.(type) {
+	case *Call:
+		return r.GetType() == Call_ACCEPT, r
+	}
+
+	return false, nil
+}
+
 func IsOfferDecline(e flow.Message) (bool, *Call) {
 	switch r := e.(type) {
 	case *Call:
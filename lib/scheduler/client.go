@@ -2,6 +2,8 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/ondrej-smola/mesos-go-http/lib/client"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
@@ -9,8 +11,6 @@ import (
 	"github.com/pkg/errors"
 )
 
-var ErrBufferFull = errors.New("Scheduler: buffer is full")
-
 type (
 	Opt func(c *Client)
 
@@ -24,8 +24,18 @@ type (
 	// Handles thread-safe communication with Mesos client
 	// Supports multiple concurrent read and write requests
 	// First push request must be subscribe call
+	// After subscribe or event stream failure all requests return typed error (ErrSubscribeFailed, ErrDisconnected)
+	// Error event from Mesos closes client with ErrFrameworkError by default (see WithErrorPolicy)
 	Client struct {
-		bufferSize int
+		bufferSize         int
+		kindBufferSizes    map[string]int
+		overflowPolicy     OverflowPolicy
+		onDrop             func(flow.Message)
+		connectionMessages bool
+		errorPolicy        EventPolicy
+		failurePolicy      EventPolicy
+		onError            func(*Event)
+		onFailure          func(*Event)
 
 		client client.Client
 
@@ -33,13 +43,17 @@ type (
 		streamId string
 
 		// buffer for events from Mesos and for loopback message (not mesos Call)
-		buffer chan flow.Message
+		buffer *buffer
 		// write request queue
 		write chan chan *event
 
 		ctx    context.Context
 		cancel context.CancelFunc
 
+		// error that caused client to close
+		err   error
+		errMu sync.Mutex
+
 		log log.Logger
 	}
 )
@@ -56,6 +70,68 @@ func WithBufferSize(size int) Opt {
 	}
 }
 
+// Limit number of buffered messages of given kind (message name e.g. Event_OFFERS.String()),
+// messages of given kind are also limited by total buffer size.
+func WithMessageBufferSize(name string, size int) Opt {
+	if size <= 0 {
+		panic(fmt.Sprintf("Buffer size for %v must be > 0, is %v", name, size))
+	}
+
+	return func(c *Client) {
+		c.kindBufferSizes[name] = size
+	}
+}
+
+// What to do when buffer is full, default is OVERFLOW_BLOCK
+func WithOverflowPolicy(p OverflowPolicy) Opt {
+	return func(c *Client) {
+		c.overflowPolicy = p
+	}
+}
+
+// Called for every message dropped because of overflow policy
+func WithDroppedFunc(f func(flow.Message)) Opt {
+	return func(c *Client) {
+		c.onDrop = f
+	}
+}
+
+// Emit ConnectionMessage after subscribe and after event stream failure
+func WithConnectionMessages() Opt {
+	return func(c *Client) {
+		c.connectionMessages = true
+	}
+}
+
+// How to handle error events, default is EVENT_FAIL.
+// Mesos sends error event when framework cannot continue (e.g. it was removed), see IsRecoverable.
+func WithErrorPolicy(p EventPolicy) Opt {
+	return func(c *Client) {
+		c.errorPolicy = p
+	}
+}
+
+// How to handle failure events (agent or executor lost), default is EVENT_DELIVER
+func WithFailurePolicy(p EventPolicy) Opt {
+	return func(c *Client) {
+		c.failurePolicy = p
+	}
+}
+
+// Called from event stream reader for every error event before policy is applied, must not block
+func WithErrorFunc(f func(*Event)) Opt {
+	return func(c *Client) {
+		c.onError = f
+	}
+}
+
+// Called from event stream reader for every failure event before policy is applied, must not block
+func WithFailureFunc(f func(*Event)) Opt {
+	return func(c *Client) {
+		c.onFailure = f
+	}
+}
+
 var _ = flow.Flow(&Client{})
 
 func Blueprint(client client.Client, opts ...Opt) flow.SinkBlueprint {
@@ -74,19 +150,30 @@ func New(client client.Client, opts ...Opt) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	c := &Client{
-		bufferSize: 16,
-		ctx:        ctx,
-		cancel:     cancel,
-		client:     client,
-		write:      make(chan chan *event),
-		log:        log.NewNopLogger(),
+		bufferSize:      16,
+		kindBufferSizes: make(map[string]int),
+		overflowPolicy:  OVERFLOW_BLOCK,
+		errorPolicy:     EVENT_FAIL,
+		failurePolicy:   EVENT_DELIVER,
+		ctx:             ctx,
+		cancel:          cancel,
+		client:          client,
+		write:           make(chan chan *event),
+		log:             log.NewNopLogger(),
 	}
 
 	for _, o := range opts {
 		o(c)
 	}
 
-	c.buffer = make(chan flow.Message, c.bufferSize)
+	onDrop := func(m flow.Message) {
+		c.log.Log("event", "message_dropped", "name", m.Name(), "policy", c.overflowPolicy)
+		if c.onDrop != nil {
+			c.onDrop(m)
+		}
+	}
+
+	c.buffer = newBuffer(c.bufferSize, c.kindBufferSizes, c.overflowPolicy, onDrop)
 
 	go c.schedulerLoop()
 
@@ -102,7 +189,7 @@ func (c *Client) Push(ev flow.Message, ctx context.Context) error {
 	case <-ctx.Done():
 		return ctx.Err()
 	case <-c.ctx.Done():
-		return c.ctx.Err()
+		return c.Err()
 	}
 
 	res := <-req
@@ -115,20 +202,19 @@ func (c *Client) Push(ev flow.Message, ctx context.Context) error {
 }
 
 func (c *Client) Pull(ctx context.Context) (flow.Message, error) {
-	// read messages from buffer to allow pull after context cancelled
-	select {
-	case ev := <-c.buffer:
-		return ev, nil
-	default:
-	}
+	for {
+		// read messages from buffer to allow pull after context cancelled
+		if ev, ok := c.buffer.pop(); ok {
+			return ev, nil
+		}
 
-	select {
-	case ev := <-c.buffer:
-		return ev, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-c.ctx.Done():
-		return nil, c.ctx.Err()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		} else if c.ctx.Err() != nil {
+			return nil, c.Err()
+		}
+
+		c.buffer.wait(ctx, c.ctx)
 	}
 }
 
@@ -139,6 +225,33 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Returns number of messages dropped because of overflow policy by message name
+func (c *Client) Dropped() map[string]uint64 {
+	return c.buffer.droppedCounts()
+}
+
+// Returns error that caused client to close, context.Canceled when closed using Close
+// and nil when client is not closed
+func (c *Client) Err() error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.ctx.Err()
+}
+
+func (c *Client) fail(err error) {
+	c.errMu.Lock()
+	if c.err == nil && c.ctx.Err() == nil {
+		c.err = err
+	}
+	c.errMu.Unlock()
+	c.cancel()
+}
+
 // implements flow.Sink interface
 func (c *Client) IsSink() {}
 
@@ -153,7 +266,7 @@ func (c *Client) schedulerLoop() {
 			if c.streamId == "" {
 				resp, err := c.subscribe(<-w)
 				if err != nil {
-					c.cancel()
+					c.fail(err)
 				} else {
 					c.log.Log("event", "subscribed", "stream-id", c.streamId)
 					go c.readerLoop(resp)
@@ -180,10 +293,11 @@ func (c *Client) doWrite(w chan *event, streamId string) {
 			w <- &event{err: err}
 		}
 	default:
-		select {
-		case c.buffer <- ev.event:
-		default:
-			w <- &event{err: ErrBufferFull}
+		if err := c.buffer.offer(ev.event); err != nil {
+			if c.overflowPolicy == OVERFLOW_FAIL {
+				c.fail(err)
+			}
+			w <- &event{err: err}
 		}
 	}
 }
@@ -197,11 +311,14 @@ func (c *Client) subscribe(ev *event) (client.Response, error) {
 
 	// set Close(true) because subscribe call should not reuse connections
 	if res, err := c.client.Do(subscribe, c.ctx, client.WithClose(true)); err != nil {
-		return nil, err
+		return nil, ErrSubscribeFailed{Err: err}
 	} else {
 		sid := res.StreamId()
 		if sid == "" {
-			return nil, errors.Errorf("Mesos is expected to set %v but it is empty", client.MESOS_STREAM_ID_HEADER)
+			res.Close()
+			return nil, ErrSubscribeFailed{
+				Err: errors.Errorf("Mesos is expected to set %v but it is empty", client.MESOS_STREAM_ID_HEADER),
+			}
 		}
 		c.streamId = sid
 		return res, nil
@@ -212,19 +329,73 @@ func (c *Client) readerLoop(resp client.Response) {
 	defer c.log.Log("event", "reader_loop_cancelled")
 	defer resp.Close()
 
+	if c.connectionMessages {
+		if err := c.buffer.put(&ConnectionMessage{State: CONNECTED, StreamId: c.streamId}, c.ctx); err != nil {
+			c.failOverflow(err)
+			return
+		}
+	}
+
 	for {
 		msg := &Event{}
 		err := resp.Read(msg)
 		if err != nil {
 			c.log.Log("event", "reader_loop", "err", err)
-			c.cancel()
-			return
-		} else {
-			select {
-			case c.buffer <- flow.Message(msg):
-			case <-c.ctx.Done():
-				return
+			err = ErrDisconnected{StreamId: c.streamId, Err: err}
+			if c.connectionMessages {
+				if err := c.buffer.offer(&ConnectionMessage{State: DISCONNECTED, StreamId: c.streamId, Err: err}); err != nil {
+					c.log.Log("event", "connection_message_dropped", "err", err)
+				}
 			}
+			c.fail(err)
+			return
+		}
+
+		policy, policyErr := c.policy(msg)
+		if policy == EVENT_DROP {
+			continue
+		}
+
+		if err := c.buffer.put(msg, c.ctx); err != nil {
+			c.failOverflow(err)
+			return
+		}
+
+		if policy == EVENT_FAIL {
+			c.log.Log("event", "reader_loop", "err", policyErr)
+			c.fail(policyErr)
+			return
+		}
+	}
+}
+
+// Calls callbacks and returns policy for event with error used when policy is EVENT_FAIL
+func (c *Client) policy(ev *Event) (EventPolicy, error) {
+	switch ev.GetType() {
+	case Event_ERROR:
+		if c.onError != nil {
+			c.onError(ev)
+		}
+		return c.errorPolicy, ErrFrameworkError{Message: ev.GetError().GetMessage()}
+	case Event_FAILURE:
+		if c.onFailure != nil {
+			c.onFailure(ev)
 		}
+		f := ev.GetFailure()
+		return c.failurePolicy, ErrFailure{
+			AgentId:    f.GetAgentId().GetValue(),
+			ExecutorId: f.GetExecutorId().GetValue(),
+			Status:     f.Status,
+		}
+	default:
+		return EVENT_DELIVER, nil
+	}
+}
+
+// Fails client when buffer overflowed, context errors are ignored (client is already closed)
+func (c *Client) failOverflow(err error) {
+	if err == ErrBufferFull {
+		c.log.Log("event", "buffer_overflow", "err", err)
+		c.fail(err)
 	}
 }
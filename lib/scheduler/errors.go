@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrBufferFull = errors.New("Scheduler: buffer is full")
+
+// Message of error event sent by Mesos when framework was torn down
+const FRAMEWORK_REMOVED_MESSAGE = "Framework has been removed"
+
+type (
+	// Subscribe call failed, client is closed
+	ErrSubscribeFailed struct {
+		Err error
+	}
+
+	// Reading from event stream failed, client is closed
+	ErrDisconnected struct {
+		StreamId string
+		Err      error
+	}
+
+	// No message was received from Mesos within expected time
+	ErrHeartbeatMissed struct {
+		// number of heartbeat intervals without received message
+		Missed int
+		// configured heartbeat interval (or deadline when interval is not known)
+		Interval time.Duration
+		// time of last received message, zero when nothing was received
+		LastHeartbeat time.Time
+	}
+
+	// Error event received from Mesos (e.g. framework was removed), client is closed
+	ErrFrameworkError struct {
+		Message string
+	}
+
+	// Failure event received from Mesos (agent or executor lost), returned only when configured by failure policy
+	ErrFailure struct {
+		AgentId    string
+		ExecutorId string
+		// exit status of executor, nil when not known
+		Status *int32
+	}
+)
+
+func (e ErrSubscribeFailed) Error() string {
+	return fmt.Sprintf("Scheduler: subscribe failed, cause %v", e.Err)
+}
+
+func (e ErrDisconnected) Error() string {
+	return fmt.Sprintf("Scheduler: disconnected (stream %v), cause %v", e.StreamId, e.Err)
+}
+
+func (e ErrHeartbeatMissed) Error() string {
+	return fmt.Sprintf(
+		"Scheduler: missed %v heartbeat(s) (interval %v), last heartbeat at %v",
+		e.Missed, e.Interval, e.LastHeartbeat,
+	)
+}
+
+func (e ErrFrameworkError) Error() string {
+	return fmt.Sprintf("Scheduler: error received from Mesos: %v", e.Message)
+}
+
+func (e ErrFailure) Error() string {
+	status := "unknown"
+	if e.Status != nil {
+		status = fmt.Sprint(*e.Status)
+	}
+	return fmt.Sprintf("Scheduler: failure of agent %v executor %v (status %v)", e.AgentId, e.ExecutorId, status)
+}
+
+// Returns if error is ErrSubscribeFailed and its cause
+func IsSubscribeFailed(err error) (bool, error) {
+	e, ok := errors.Cause(err).(ErrSubscribeFailed)
+	return ok, e.Err
+}
+
+// Returns if error is ErrDisconnected and its cause
+func IsDisconnected(err error) (bool, error) {
+	e, ok := errors.Cause(err).(ErrDisconnected)
+	return ok, e.Err
+}
+
+// Returns if error is ErrHeartbeatMissed
+func IsHeartbeatMissed(err error) (bool, ErrHeartbeatMissed) {
+	e, ok := errors.Cause(err).(ErrHeartbeatMissed)
+	return ok, e
+}
+
+// Returns if error is ErrFrameworkError and its message
+func IsFrameworkError(err error) (bool, string) {
+	e, ok := errors.Cause(err).(ErrFrameworkError)
+	return ok, e.Message
+}
+
+// Returns true when error is ErrFrameworkError reporting that framework was removed
+func IsFrameworkRemoved(err error) bool {
+	ok, msg := IsFrameworkError(err)
+	return ok && IsFrameworkRemovedMessage(msg)
+}
+
+// Returns true when message of error event reports that framework was removed
+func IsFrameworkRemovedMessage(msg string) bool {
+	return strings.Contains(strings.ToLower(msg), strings.ToLower(FRAMEWORK_REMOVED_MESSAGE))
+}
+
+// Returns if error is ErrFailure
+func IsFailure(err error) (bool, ErrFailure) {
+	e, ok := errors.Cause(err).(ErrFailure)
+	return ok, e
+}
+
+// Returns false when subscribing again cannot succeed (framework was removed)
+func IsRecoverable(err error) bool {
+	return !IsFrameworkRemoved(err)
+}
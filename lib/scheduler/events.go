@@ -16,6 +16,26 @@ func (e *Event) Name() string {
 
 var _ = flow.Message(&Event{})
 
+// Message wrapping scheduler event (e.g. status update with acknowledgement handle),
+// stages and Is* functions handle it as wrapped event
+type EventMessage interface {
+	flow.Message
+	Event() *Event
+}
+
+// Returns event of message, message is either event or EventMessage
+func EventOf(m flow.Message) (*Event, bool) {
+	switch r := m.(type) {
+	case *Event:
+		return r, true
+	case EventMessage:
+		e := r.Event()
+		return e, e != nil
+	}
+
+	return nil, false
+}
+
 // Custom message useful for sending something as message
 type PingMessage struct{}
 
@@ -24,3 +44,85 @@ func (p *PingMessage) Name() string {
 }
 
 var _ = flow.Message(&PingMessage{})
+
+type ConnectionState int
+
+const (
+	CONNECTED ConnectionState = iota
+	DISCONNECTED
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case CONNECTED:
+		return "connected"
+	case DISCONNECTED:
+		return "disconnected"
+	default:
+		return "unknown"
+	}
+}
+
+// Synthetic message announcing change of connection to Mesos (see WithConnectionMessages)
+type ConnectionMessage struct {
+	State    ConnectionState
+	StreamId string
+	// Set when disconnected
+	Err error
+}
+
+func (c *ConnectionMessage) Name() string {
+	return c.State.String()
+}
+
+var _ = flow.Message(&ConnectionMessage{})
+
+func IsConnectionMessage(e flow.Message) (bool, *ConnectionMessage) {
+	switch r := e.(type) {
+	case *ConnectionMessage:
+		return true, r
+	}
+
+	return false, nil
+}
+
+// Defines how client handles error and failure events received from Mesos
+type EventPolicy int
+
+const (
+	// Event is returned by pull
+	EVENT_DELIVER EventPolicy = iota
+	// Event is only passed to callback (if configured)
+	EVENT_DROP
+	// Event is returned by pull and client is closed with typed error (ErrFrameworkError, ErrFailure)
+	EVENT_FAIL
+)
+
+func (p EventPolicy) String() string {
+	switch p {
+	case EVENT_DELIVER:
+		return "deliver"
+	case EVENT_DROP:
+		return "drop"
+	case EVENT_FAIL:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+func IsErrorMessage(e flow.Message) (bool, *Event) {
+	if r, ok := EventOf(e); ok {
+		return r.GetType() == Event_ERROR, r
+	}
+
+	return false, nil
+}
+
+func IsFailureMessage(e flow.Message) (bool, *Event) {
+	if r, ok := EventOf(e); ok {
+		return r.GetType() == Event_FAILURE, r
+	}
+
+	return false, nil
+}
@@ -15,6 +15,15 @@ func (c *Call) With(opts ...CallOpt) *Call {
 	return c
 }
 
+// Sets framework id of call, empty id is ignored
+func FrameworkId(id string) CallOpt {
+	return func(c *Call) {
+		if id != "" {
+			c.FrameworkId = &mesos.FrameworkID{Value: mesos.Strp(id)}
+		}
+	}
+}
+
 func RefuseSeconds(dur time.Duration) CallOpt {
 	filter := &mesos.Filters{
 		RefuseSeconds: mesos.F64p(dur.Seconds()),
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/pkg/errors"
+)
+
+// Returns true when framework declares MULTI_ROLE capability
+func IsMultiRole(info *mesos.FrameworkInfo) bool {
+	for _, c := range info.GetCapabilities() {
+		if c.GetType() == mesos.FrameworkInfo_Capability_MULTI_ROLE {
+			return true
+		}
+	}
+	return false
+}
+
+// Sets roles of framework and declares MULTI_ROLE capability (single role field is cleared)
+func WithRoles(info *mesos.FrameworkInfo, roles ...string) *mesos.FrameworkInfo {
+	info.Role = nil
+	info.Roles = roles
+
+	if !IsMultiRole(info) {
+		info.Capabilities = append(info.Capabilities, &mesos.FrameworkInfo_Capability{
+			Type: mesos.FrameworkInfo_Capability_MULTI_ROLE.Enum(),
+		})
+	}
+
+	return info
+}
+
+// Returns roles of framework (roles of multi-role framework or its single role)
+func FrameworkRoles(info *mesos.FrameworkInfo) []string {
+	if IsMultiRole(info) {
+		return info.GetRoles()
+	}
+	return []string{info.GetRole()}
+}
+
+// Revives offers for all roles of framework
+func ReviveAll() *Call {
+	return Revive()
+}
+
+// Suppresses offers for all roles of framework
+func SuppressAll() *Call {
+	return Suppress()
+}
+
+// Revives offers for single role, empty role is rejected as it would revive all roles
+func ReviveRole(role string) *Call {
+	if role == "" {
+		panic("Role must not be empty")
+	}
+	return Revive(role)
+}
+
+// Suppresses offers for single role, empty role is rejected as it would suppress all roles
+func SuppressRole(role string) *Call {
+	if role == "" {
+		panic("Role must not be empty")
+	}
+	return Suppress(role)
+}
+
+// Checks that resources of tasks and executors launched by operations carry allocation role of offer.
+// Offers without allocation role (single role frameworks) are not checked.
+func ValidateAllocation(offer *mesos.Offer, ops ...*mesos.Offer_Operation) error {
+	role := offer.AllocationRole()
+	if role == "" {
+		return nil
+	}
+
+	check := func(what string, rs []*mesos.Resource) error {
+		for _, r := range rs {
+			if r.AllocationRole() != role {
+				return errors.Errorf(
+					"Allocation: %v resource %v allocated to %q, offer %v is allocated to %q",
+					what, r.GetName(), r.AllocationRole(), offer.GetId().GetValue(), role,
+				)
+			}
+		}
+		return nil
+	}
+
+	checkTask := func(t *mesos.TaskInfo) error {
+		if err := check("task "+t.GetTaskId().GetValue(), t.GetResources()); err != nil {
+			return err
+		}
+		if e := t.GetExecutor(); e != nil {
+			return check("executor "+e.GetExecutorId().GetValue(), e.GetResources())
+		}
+		return nil
+	}
+
+	for _, op := range ops {
+		switch op.GetType() {
+		case mesos.Offer_Operation_LAUNCH:
+			for _, t := range op.GetLaunch().GetTaskInfos() {
+				if err := checkTask(t); err != nil {
+					return err
+				}
+			}
+		case mesos.Offer_Operation_LAUNCH_GROUP:
+			lg := op.GetLaunchGroup()
+			if err := check("executor "+lg.GetExecutor().GetExecutorId().GetValue(), lg.GetExecutor().GetResources()); err != nil {
+				return err
+			}
+			for _, t := range lg.GetTaskGroup().GetTasks() {
+				if err := checkTask(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,11 +2,17 @@ package scheduler_test
 
 import (
 	"context"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/client"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
 	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -73,7 +79,7 @@ var _ = Describe("Scheduler", func() {
 		close(done)
 	})
 
-	It("Return context cancelled after subscribe failure", func(done Done) {
+	It("Return subscribe failed error after subscribe failure", func(done Done) {
 		cl := client.NewTestChanClient()
 		sched := New(cl)
 		ctx := context.Background()
@@ -85,15 +91,16 @@ var _ = Describe("Scheduler", func() {
 			cl.ReqOut <- &client.TestClientResponseOrError{Err: err}
 		}()
 
-		Expect(sched.Push(subscribe, ctx)).To(Equal(err))
-		Expect(sched.Push(Teardown(), ctx)).To(Equal(context.Canceled))
+		Expect(sched.Push(subscribe, ctx)).To(Equal(ErrSubscribeFailed{Err: err}))
+		Expect(sched.Push(Teardown(), ctx)).To(Equal(ErrSubscribeFailed{Err: err}))
 		close(done)
 	})
 
-	It("Return context cancelled after response read failure", func(done Done) {
+	It("Return disconnected error after response read failure", func(done Done) {
 		cl := client.NewTestChanClient()
 		sched := New(cl)
 		ctx := context.Background()
+		err := errors.New("Failed")
 
 		go func() {
 			defer GinkgoRecover()
@@ -102,12 +109,56 @@ var _ = Describe("Scheduler", func() {
 			respChan := client.NewTestChanResponse("1")
 			cl.ReqOut <- &client.TestClientResponseOrError{Resp: respChan}
 			<-respChan.ReadIn
-			respChan.ReadOut <- &client.TestMessageOrError{Err: errors.New("Failed")}
+			respChan.ReadOut <- &client.TestMessageOrError{Err: err}
+			<-respChan.CloseIn
+			respChan.CloseOut <- nil
 		}()
 
 		Expect(sched.Push(subscribe, ctx)).To(Succeed())
 		_, e := sched.Pull(ctx)
-		Expect(e).To(Equal(context.Canceled))
+		Expect(e).To(Equal(ErrDisconnected{StreamId: "1", Err: err}))
+		ok, cause := IsDisconnected(e)
+		Expect(ok).To(BeTrue())
+		Expect(cause).To(Equal(err))
+		close(done)
+	})
+
+	It("Emit connection messages when configured", func(done Done) {
+		cl := client.NewTestChanClient()
+		sched := New(cl, WithConnectionMessages())
+		ctx := context.Background()
+		err := errors.New("Failed")
+
+		go func() {
+			defer GinkgoRecover()
+			<-cl.ReqIn
+
+			respChan := client.NewTestChanResponse("1")
+			cl.ReqOut <- &client.TestClientResponseOrError{Resp: respChan}
+			<-respChan.ReadIn
+			respChan.ReadOut <- &client.TestMessageOrError{Msg: TestHeartbeat()}
+			<-respChan.ReadIn
+			respChan.ReadOut <- &client.TestMessageOrError{Err: err}
+			<-respChan.CloseIn
+			respChan.CloseOut <- nil
+		}()
+
+		Expect(sched.Push(subscribe, ctx)).To(Succeed())
+		msg, e := sched.Pull(ctx)
+		Expect(e).To(Succeed())
+		Expect(msg).To(Equal(&ConnectionMessage{State: CONNECTED, StreamId: "1"}))
+
+		msg, e = sched.Pull(ctx)
+		Expect(e).To(Succeed())
+		Expect(msg).To(Equal(TestHeartbeat()))
+
+		disconnected := ErrDisconnected{StreamId: "1", Err: err}
+		msg, e = sched.Pull(ctx)
+		Expect(e).To(Succeed())
+		Expect(msg).To(Equal(&ConnectionMessage{State: DISCONNECTED, StreamId: "1", Err: disconnected}))
+
+		_, e = sched.Pull(ctx)
+		Expect(e).To(Equal(disconnected))
 		close(done)
 	})
 
@@ -141,7 +192,8 @@ var _ = Describe("Scheduler", func() {
 		Expect(msg).To(Equal(testMsg))
 
 		_, e = sched.Pull(ctx)
-		Expect(e).To(Equal(context.Canceled))
+		ok, _ := IsDisconnected(e)
+		Expect(ok).To(BeTrue())
 
 		close(done)
 	})
@@ -177,6 +229,207 @@ var _ = Describe("Scheduler", func() {
 		close(done)
 	})
 
+	It("Close client with framework error after error event", func(done Done) {
+		cl := client.NewTestChanClient()
+		var received *Event
+		sched := New(cl, WithErrorFunc(func(e *Event) { received = e }))
+		ctx := context.Background()
+		errorEvent := &Event{Type: Event_ERROR.Enum(), Error: &Event_Error{Message: mesos.Strp(FRAMEWORK_REMOVED_MESSAGE)}}
+
+		go func() {
+			defer GinkgoRecover()
+			<-cl.ReqIn
+			respChan := client.NewTestChanResponse("1")
+			cl.ReqOut <- &client.TestClientResponseOrError{Resp: respChan}
+			<-respChan.ReadIn
+			respChan.ReadOut <- &client.TestMessageOrError{Msg: errorEvent}
+			<-respChan.CloseIn
+			respChan.CloseOut <- nil
+		}()
+
+		Expect(sched.Push(subscribe, ctx)).To(Succeed())
+		msg, err := sched.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(errorEvent))
+		Expect(received).To(Equal(errorEvent))
+
+		_, err = sched.Pull(ctx)
+		Expect(err).To(Equal(ErrFrameworkError{Message: FRAMEWORK_REMOVED_MESSAGE}))
+		Expect(IsFrameworkRemoved(err)).To(BeTrue())
+		Expect(IsFrameworkRemovedMessage("framework has been removed")).To(BeTrue())
+		Expect(IsFrameworkRemovedMessage("boom")).To(BeFalse())
+		Expect(IsRecoverable(err)).To(BeFalse())
+		Expect(sched.Push(Teardown(), ctx)).To(Equal(err))
+		close(done)
+	})
+
+	It("Handle failure events according to policy", func(done Done) {
+		cl := client.NewTestChanClient()
+		failures := 0
+		sched := New(cl, WithFailurePolicy(EVENT_DROP), WithFailureFunc(func(*Event) { failures++ }))
+		ctx := context.Background()
+		failure := &Event{
+			Type:    Event_FAILURE.Enum(),
+			Failure: &Event_Failure{AgentId: &mesos.AgentID{Value: mesos.Strp("agent")}},
+		}
+
+		go func() {
+			defer GinkgoRecover()
+			<-cl.ReqIn
+			respChan := client.NewTestChanResponse("1")
+			cl.ReqOut <- &client.TestClientResponseOrError{Resp: respChan}
+			<-respChan.ReadIn
+			respChan.ReadOut <- &client.TestMessageOrError{Msg: failure}
+			<-respChan.ReadIn
+			respChan.ReadOut <- &client.TestMessageOrError{Msg: TestHeartbeat()}
+		}()
+
+		Expect(sched.Push(subscribe, ctx)).To(Succeed())
+		msg, err := sched.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(TestHeartbeat()))
+		Expect(failures).To(Equal(1))
+		close(done)
+	})
+
+	Describe("Overflow policy", func() {
+		messageEvent := func(data string) *Event {
+			return &Event{Type: Event_MESSAGE.Enum(), Message: &Event_Message{
+				AgentId:    &mesos.AgentID{Value: mesos.Strp("agent")},
+				ExecutorId: &mesos.ExecutorID{Value: mesos.Strp("executor")},
+				Data:       []byte(data),
+			}}
+		}
+
+		offersEvent := func(ids ...string) *Event {
+			ev := &Event{Type: Event_OFFERS.Enum(), Offers: &Event_Offers{}}
+			for _, id := range ids {
+				ev.Offers.Offers = append(ev.Offers.Offers, &mesos.Offer{
+					Id:          &mesos.OfferID{Value: mesos.Strp(id)},
+					FrameworkId: &mesos.FrameworkID{Value: mesos.Strp("fw")},
+					AgentId:     &mesos.AgentID{Value: mesos.Strp("agent")},
+					Hostname:    mesos.Strp("localhost"),
+				})
+			}
+			return ev
+		}
+
+		// subscribes and sends all messages, returned channel is closed when all messages were processed
+		serve := func(cl *client.TestChanClient, msgs ...*Event) chan struct{} {
+			processed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				<-cl.ReqIn
+				respChan := client.NewTestChanResponse("1")
+				cl.ReqOut <- &client.TestClientResponseOrError{Resp: respChan}
+				for _, m := range msgs {
+					<-respChan.ReadIn
+					respChan.ReadOut <- &client.TestMessageOrError{Msg: m}
+				}
+				select {
+				case <-respChan.ReadIn:
+				case <-respChan.CloseIn:
+					respChan.CloseOut <- nil
+				}
+				close(processed)
+			}()
+			return processed
+		}
+
+		It("Drop oldest", func(done Done) {
+			cl := client.NewTestChanClient()
+			dropped := 0
+			sched := New(cl, WithBufferSize(1), WithOverflowPolicy(OVERFLOW_DROP_OLDEST), WithDroppedFunc(func(flow.Message) {
+				dropped++
+			}))
+			ctx := context.Background()
+			processed := serve(cl, messageEvent("1"), messageEvent("2"), messageEvent("3"))
+
+			Expect(sched.Push(subscribe, ctx)).To(Succeed())
+			<-processed
+
+			msg, err := sched.Pull(ctx)
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(messageEvent("3")))
+			Expect(sched.Dropped()).To(Equal(map[string]uint64{Event_MESSAGE.String(): 2}))
+			Expect(dropped).To(Equal(2))
+			close(done)
+		})
+
+		It("Drop newest", func(done Done) {
+			cl := client.NewTestChanClient()
+			sched := New(cl, WithBufferSize(1), WithOverflowPolicy(OVERFLOW_DROP_NEWEST))
+			ctx := context.Background()
+			processed := serve(cl, messageEvent("1"), messageEvent("2"), messageEvent("3"))
+
+			Expect(sched.Push(subscribe, ctx)).To(Succeed())
+			<-processed
+
+			Expect(sched.Push(&PingMessage{}, ctx)).To(Equal(ErrBufferFull))
+
+			msg, err := sched.Pull(ctx)
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(messageEvent("1")))
+			Expect(sched.Dropped()).To(Equal(map[string]uint64{Event_MESSAGE.String(): 2, "ping": 1}))
+			close(done)
+		})
+
+		It("Coalesce offers", func(done Done) {
+			cl := client.NewTestChanClient()
+			sched := New(cl, WithBufferSize(1), WithOverflowPolicy(OVERFLOW_COALESCE_OFFERS))
+			ctx := context.Background()
+			processed := serve(cl, offersEvent("1"), offersEvent("2", "3"))
+
+			Expect(sched.Push(subscribe, ctx)).To(Succeed())
+			<-processed
+
+			msg, err := sched.Pull(ctx)
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(offersEvent("1", "2", "3")))
+			Expect(sched.Dropped()).To(BeEmpty())
+			close(done)
+		})
+
+		It("Limit buffer size per message kind", func(done Done) {
+			cl := client.NewTestChanClient()
+			sched := New(
+				cl,
+				WithMessageBufferSize(Event_MESSAGE.String(), 1),
+				WithOverflowPolicy(OVERFLOW_DROP_NEWEST),
+			)
+			ctx := context.Background()
+			processed := serve(cl, messageEvent("1"), messageEvent("2"), TestHeartbeat())
+
+			Expect(sched.Push(subscribe, ctx)).To(Succeed())
+			<-processed
+
+			msg, err := sched.Pull(ctx)
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(messageEvent("1")))
+			msg, err = sched.Pull(ctx)
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(TestHeartbeat()))
+			close(done)
+		})
+
+		It("Fail", func(done Done) {
+			cl := client.NewTestChanClient()
+			sched := New(cl, WithBufferSize(1), WithOverflowPolicy(OVERFLOW_FAIL))
+			ctx := context.Background()
+			processed := serve(cl, messageEvent("1"), messageEvent("2"))
+
+			Expect(sched.Push(subscribe, ctx)).To(Succeed())
+			<-processed
+
+			msg, err := sched.Pull(ctx)
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(messageEvent("1")))
+			_, err = sched.Pull(ctx)
+			Expect(err).To(Equal(ErrBufferFull))
+			close(done)
+		})
+	})
+
 	It("Return context cancelled after close", func(done Done) {
 		sched := New(client.NewTestChanClient())
 		ctx := context.Background()
@@ -240,6 +493,29 @@ var _ = Describe("Scheduler", func() {
 		close(done)
 	})
 
+	It("Block idle pulls until message is available", func(done Done) {
+		sched := New(client.NewTestChanClient())
+		ctx, cancel := context.WithCancel(context.Background())
+		pullCtx := &countingContext{Context: ctx}
+
+		pulled := make(chan error)
+		for i := 0; i < 2; i++ {
+			go func() {
+				_, err := sched.Pull(pullCtx)
+				pulled <- err
+			}()
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		// pulls check context once before waiting and should not wake each other
+		Expect(atomic.LoadInt64(&pullCtx.checks)).To(BeNumerically("<=", 4))
+
+		cancel()
+		Expect(<-pulled).To(Equal(context.Canceled))
+		Expect(<-pulled).To(Equal(context.Canceled))
+		close(done)
+	})
+
 	It("Return on push request context cancelled", func(done Done) {
 		cl := client.NewTestChanClient()
 		sched := New(cl, WithBufferSize(0))
@@ -258,3 +534,182 @@ var _ = Describe("Scheduler", func() {
 		close(done)
 	})
 })
+
+var _ = Describe("Task store", func() {
+
+	status := func(id string, state mesos.TaskState) *mesos.TaskStatus {
+		return &mesos.TaskStatus{
+			TaskId:  &mesos.TaskID{Value: mesos.Strp(id)},
+			AgentId: &mesos.AgentID{Value: mesos.Strp("agent-" + id)},
+			State:   state.Enum(),
+		}
+	}
+
+	fill := func(s TaskStore) {
+		Expect(s.Put(&Task{TaskId: mesos.Strp("1"), Desired: DESIRED_RUNNING.Enum()})).To(Succeed())
+		Expect(s.Put(&Task{TaskId: mesos.Strp("2"), Desired: DESIRED_RUNNING.Enum()})).To(Succeed())
+		Expect(s.Update(status("1", mesos.TaskState_TASK_RUNNING))).To(Succeed())
+		Expect(s.Update(status("2", mesos.TaskState_TASK_RUNNING))).To(Succeed())
+		Expect(s.Update(status("3", mesos.TaskState_TASK_FINISHED))).To(Succeed())
+		Expect(s.SetDesired("2", DESIRED_KILLED)).To(Succeed())
+		Expect(s.Put(&Task{TaskId: mesos.Strp("4")})).To(Succeed())
+		Expect(s.Remove("4")).To(Succeed())
+	}
+
+	verify := func(s TaskStore) {
+		t, ok := s.Get("2")
+		Expect(ok).To(BeTrue())
+		Expect(t.GetAgentId()).To(Equal("agent-2"))
+		Expect(t.GetDesired()).To(Equal(DESIRED_KILLED))
+		Expect(t.GetStatus().GetState()).To(Equal(mesos.TaskState_TASK_RUNNING))
+
+		_, ok = s.Get("4")
+		Expect(ok).To(BeFalse())
+
+		Expect(s.All()).To(HaveLen(3))
+		Expect(NonTerminalTasks(s)).To(Equal(map[string]string{"1": "agent-1", "2": "agent-2"}))
+	}
+
+	It("Memory store", func() {
+		s := NewMemoryTaskStore()
+		fill(s)
+		verify(s)
+
+		// returned tasks are copies
+		t, _ := s.Get("1")
+		t.Desired = DESIRED_KILLED.Enum()
+		t, _ = s.Get("1")
+		Expect(t.GetDesired()).To(Equal(DESIRED_RUNNING))
+	})
+
+	It("File store", func() {
+		dir, err := ioutil.TempDir("", "taskstore")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "tasks")
+
+		s, err := NewFileTaskStore(path)
+		Expect(err).To(Succeed())
+		fill(s)
+		verify(s)
+		Expect(s.Close()).To(Succeed())
+
+		s, err = NewFileTaskStore(path)
+		Expect(err).To(Succeed())
+		verify(s)
+
+		Expect(s.Compact()).To(Succeed())
+		Expect(s.Update(status("1", mesos.TaskState_TASK_FAILED))).To(Succeed())
+		Expect(s.Close()).To(Succeed())
+
+		s, err = NewFileTaskStore(path)
+		Expect(err).To(Succeed())
+		Expect(NonTerminalTasks(s)).To(Equal(map[string]string{"2": "agent-2"}))
+		Expect(s.Close()).To(Succeed())
+	})
+
+	It("File store with incomplete last record", func() {
+		dir, err := ioutil.TempDir("", "taskstore")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+
+		tear := map[string]func(path string){
+			"record": func(path string) {
+				fi, err := os.Stat(path)
+				Expect(err).To(Succeed())
+				Expect(os.Truncate(path, fi.Size()-2)).To(Succeed())
+			},
+			"header": func(path string) {
+				f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+				Expect(err).To(Succeed())
+				_, err = f.Write([]byte("12"))
+				Expect(err).To(Succeed())
+				Expect(f.Close()).To(Succeed())
+			},
+		}
+
+		for name, t := range tear {
+			path := filepath.Join(dir, name)
+
+			s, err := NewFileTaskStore(path)
+			Expect(err).To(Succeed())
+			fill(s)
+			Expect(s.Put(&Task{TaskId: mesos.Strp("5")})).To(Succeed())
+			Expect(s.Close()).To(Succeed())
+
+			t(path)
+
+			// torn record is dropped, complete records are kept
+			s, err = NewFileTaskStore(path)
+			Expect(err).To(Succeed(), name)
+			_, ok := s.Get("5")
+			Expect(ok).To(Equal(name == "header"), name)
+			Expect(s.Put(&Task{TaskId: mesos.Strp("6")})).To(Succeed())
+			Expect(s.Put(&Task{TaskId: mesos.Strp("7")})).To(Succeed())
+			Expect(s.Close()).To(Succeed())
+
+			s, err = NewFileTaskStore(path)
+			Expect(err).To(Succeed(), name)
+			_, ok = s.Get("5")
+			Expect(ok).To(Equal(name == "header"), name)
+			_, ok = s.Get("7")
+			Expect(ok).To(BeTrue(), name)
+			_, ok = s.Get("2")
+			Expect(ok).To(BeTrue(), name)
+			Expect(s.Close()).To(Succeed())
+		}
+	})
+})
+
+var _ = Describe("Roles", func() {
+
+	It("Declare multi-role framework", func() {
+		info := &mesos.FrameworkInfo{User: mesos.Strp("user"), Name: mesos.Strp("fw"), Role: mesos.Strp("a")}
+		Expect(IsMultiRole(info)).To(BeFalse())
+		Expect(FrameworkRoles(info)).To(Equal([]string{"a"}))
+
+		WithRoles(info, "a", "b")
+		WithRoles(info, "a", "b")
+		Expect(IsMultiRole(info)).To(BeTrue())
+		Expect(info.Capabilities).To(HaveLen(1))
+		Expect(info.Role).To(BeNil())
+		Expect(FrameworkRoles(info)).To(Equal([]string{"a", "b"}))
+	})
+
+	It("Validate allocation role of launched resources", func() {
+		offer := &mesos.Offer{
+			Id:             &mesos.OfferID{Value: mesos.Strp("1")},
+			AllocationInfo: &mesos.Resource_AllocationInfo{Role: mesos.Strp("a")},
+		}
+
+		task := func(role string) *mesos.TaskInfo {
+			cpus := &mesos.Resource{
+				Name:   mesos.Strp(string(mesos.CPUS)),
+				Type:   mesos.Value_SCALAR.Enum(),
+				Scalar: &mesos.Value_Scalar{Value: mesos.F64p(1)},
+			}
+			if role != "" {
+				cpus.WithAllocationRole(role)
+			}
+			return &mesos.TaskInfo{TaskId: &mesos.TaskID{Value: mesos.Strp("t")}, Resources: []*mesos.Resource{cpus}}
+		}
+
+		Expect(ValidateAllocation(offer, OpLaunch(task("a")))).To(Succeed())
+		Expect(ValidateAllocation(offer, OpLaunch(task("a"), task("b")))).To(HaveOccurred())
+		Expect(ValidateAllocation(offer, OpLaunchGroup(&mesos.ExecutorInfo{}, task("")))).To(HaveOccurred())
+
+		// single role offer
+		Expect(ValidateAllocation(&mesos.Offer{}, OpLaunch(task("b")))).To(Succeed())
+	})
+})
+
+// Counts context error checks (one per pull loop iteration)
+type countingContext struct {
+	context.Context
+	checks int64
+}
+
+func (c *countingContext) Err() error {
+	atomic.AddInt64(&c.checks, 1)
+	return c.Context.Err()
+}
@@ -2,7 +2,9 @@ package ack
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
@@ -11,24 +13,78 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Returned by Update.Ack when update was already acknowledged
+var ErrNotPending = errors.New("Ack: update is not pending acknowledgement")
+
 type (
 	Opt func(c *Acks)
 
+	// Observes explicit acknowledgements
+	AckMonitor interface {
+		// called when ack was sent, latency is time since update was pulled
+		Acked(latency time.Duration)
+		// called when ack could not be sent
+		AckFailed(err error)
+		// called when update was not acknowledged within timeout
+		Unacked(status *mesos.TaskStatus, age time.Duration)
+	}
+
+	// Pulled instead of status update event in explicit mode, stages see wrapped event (scheduler.EventMessage).
+	// Ack must be called once update was processed (e.g. persisted), until then Mesos keeps resending update.
+	Update struct {
+		event *scheduler.Event
+		uuid  string
+		acks  *Acks
+	}
+
+	pendingAck struct {
+		status   *mesos.TaskStatus
+		received time.Time
+		timer    *time.Timer
+	}
+
 	Acks struct {
 		via             flow.Flow
 		frameworkId     string
 		failOnFailedAck bool
-		log             log.Logger
+
+		explicit   bool
+		ackTimeout time.Duration
+		monitor    AckMonitor
+		pending    map[string]*pendingAck
+
+		log log.Logger
 		sync.RWMutex
 	}
 )
 
+const DEFAULT_ACK_TIMEOUT = 1 * time.Minute
+
 func WithLogger(l log.Logger) Opt {
 	return func(c *Acks) {
 		c.log = l
 	}
 }
 
+// Pulled status updates are not acknowledged automatically, they are returned as *Update with Ack handle.
+// Updates not acknowledged within timeout are reported (they remain pending), 0 disables timeout.
+func WithExplicitAcks(timeout time.Duration) Opt {
+	if timeout < 0 {
+		panic(fmt.Sprintf("Ack timeout must be >= 0, is %v", timeout))
+	}
+
+	return func(c *Acks) {
+		c.explicit = true
+		c.ackTimeout = timeout
+	}
+}
+
+func WithAckMonitor(m AckMonitor) Opt {
+	return func(c *Acks) {
+		c.monitor = m
+	}
+}
+
 func WithFailOnFailedAck() Opt {
 	return func(c *Acks) {
 		c.failOnFailedAck = true
@@ -47,10 +103,13 @@ func Blueprint(opts ...Opt) flow.StageBlueprint {
 
 // Automatically acknowledges all pulled update requests.
 // FrameworkId is set from subscribe call during pull.
-// By default only logs failed acks and returns pulled update message
+// By default only logs failed acks and returns pulled update message.
+// In explicit mode updates requiring acknowledgement are returned as *Update and acknowledged by application.
 func New(opts ...Opt) *Acks {
 	a := &Acks{
 		failOnFailedAck: false,
+		ackTimeout:      DEFAULT_ACK_TIMEOUT,
+		pending:         make(map[string]*pendingAck),
 		log:             log.NewNopLogger(),
 	}
 
@@ -97,16 +156,12 @@ func (a *Acks) Pull(ctx context.Context) (flow.Message, error) {
 					return nil, errors.Errorf("AgentId must be set on status update: %v", state)
 				}
 
-				call := &scheduler.Call{
-					Type: scheduler.Call_ACKNOWLEDGE.Enum(),
-					Acknowledge: &scheduler.Call_Acknowledge{
-						AgentId: state.AgentId,
-						TaskId:  state.TaskId,
-						Uuid:    state.Uuid,
-					},
-					FrameworkId: &mesos.FrameworkID{Value: &fwId},
+				if a.explicit {
+					return a.track(e), nil
 				}
 
+				call := ackCall(fwId, state)
+
 				if err := a.via.Push(call, ctx); err != nil {
 					if a.failOnFailedAck {
 						return nil, errors.Errorf("Failed to send implicit ack for %v cause: %v", e, err)
@@ -133,5 +188,124 @@ func (a *Acks) Via(f flow.Flow) {
 }
 
 func (a *Acks) Close() error {
+	a.Lock()
+	for _, p := range a.pending {
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+	}
+	a.Unlock()
+
 	return a.via.Close()
 }
+
+// Returns statuses of updates pending acknowledgement (explicit mode only)
+func (a *Acks) Pending() []*mesos.TaskStatus {
+	a.RLock()
+	defer a.RUnlock()
+
+	res := make([]*mesos.TaskStatus, 0, len(a.pending))
+	for _, p := range a.pending {
+		res = append(res, p.status)
+	}
+	return res
+}
+
+func (a *Acks) track(e *scheduler.Event) *Update {
+	status := e.Update.Status
+	uuid := string(status.Uuid)
+
+	a.Lock()
+	defer a.Unlock()
+
+	// update can be resent by Mesos before it was acknowledged
+	if _, ok := a.pending[uuid]; !ok {
+		p := &pendingAck{status: status, received: time.Now()}
+		if a.ackTimeout > 0 {
+			p.timer = time.AfterFunc(a.ackTimeout, func() { a.unacked(p) })
+		}
+		a.pending[uuid] = p
+	}
+
+	return &Update{event: e, uuid: uuid, acks: a}
+}
+
+func (a *Acks) unacked(p *pendingAck) {
+	age := time.Now().Sub(p.received)
+	a.log.Log(
+		"event", "unacked",
+		"task", p.status.TaskId.GetValue(),
+		"agent", p.status.AgentId.GetValue(),
+		"state", p.status.GetState().String(),
+		"age", age,
+	)
+
+	if a.monitor != nil {
+		a.monitor.Unacked(p.status, age)
+	}
+}
+
+func (a *Acks) ack(uuid string, ctx context.Context) error {
+	a.Lock()
+	p, ok := a.pending[uuid]
+	if ok {
+		delete(a.pending, uuid)
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+	}
+	fwId := a.frameworkId
+	a.Unlock()
+
+	if !ok {
+		return ErrNotPending
+	}
+
+	if err := a.via.Push(ackCall(fwId, p.status), ctx); err != nil {
+		// keep pending so ack can be retried
+		a.Lock()
+		if _, exists := a.pending[uuid]; !exists {
+			a.pending[uuid] = p
+		}
+		a.Unlock()
+
+		if a.monitor != nil {
+			a.monitor.AckFailed(err)
+		}
+		return errors.Wrapf(err, "Failed to send ack for task %v", p.status.TaskId.GetValue())
+	}
+
+	if a.monitor != nil {
+		a.monitor.Acked(time.Now().Sub(p.received))
+	}
+
+	return nil
+}
+
+func (u *Update) Name() string {
+	return u.event.Name()
+}
+
+// Returns status update event
+func (u *Update) Event() *scheduler.Event {
+	return u.event
+}
+
+// Sends acknowledgement of update, returns ErrNotPending when update was already acknowledged
+func (u *Update) Ack(ctx context.Context) error {
+	return u.acks.ack(u.uuid, ctx)
+}
+
+var _ = scheduler.EventMessage(&Update{})
+
+func ackCall(fwId string, state *mesos.TaskStatus) *scheduler.Call {
+	return &scheduler.Call{
+		Type: scheduler.Call_ACKNOWLEDGE.Enum(),
+		Acknowledge: &scheduler.Call_Acknowledge{
+			AgentId: state.AgentId,
+			TaskId:  state.TaskId,
+			Uuid:    state.Uuid,
+		},
+		FrameworkId: &mesos.FrameworkID{Value: &fwId},
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/tasks"
 	"github.com/pkg/errors"
 )
 
@@ -153,4 +154,131 @@ var _ = Describe("ImplicitAck state", func() {
 
 		close(done)
 	})
+
+	It("Ack explicitly", func(done Done) {
+		monitor := &testMonitor{unacked: make(chan *mesos.TaskStatus, 1)}
+		acks := New(WithExplicitAcks(20*time.Millisecond), WithAckMonitor(monitor))
+		sink := flow.NewTestFlow()
+		acks.Via(sink)
+		ctx := context.Background()
+		noAck := make(chan struct{})
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(statusUpdate)
+			sink.ExpectNoPush(50 * time.Millisecond)
+			close(noAck)
+		}()
+
+		_, err := acks.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		msg, err := acks.Pull(ctx)
+		Expect(err).To(Succeed())
+		upd, ok := msg.(*Update)
+		Expect(ok).To(BeTrue())
+		Expect(upd.Event()).To(Equal(statusUpdate))
+		Expect(acks.Pending()).To(HaveLen(1))
+
+		Eventually(monitor.unacked).Should(Receive(Equal(statusUpdate.Update.Status)))
+		<-noAck
+
+		go func() {
+			defer GinkgoRecover()
+			push := sink.ExpectPush()
+			c, ok := push.Msg.(*scheduler.Call)
+			Expect(ok).To(BeTrue())
+			Expect(c.GetType()).To(Equal(scheduler.Call_ACKNOWLEDGE))
+			Expect(c.FrameworkId.GetValue()).To(Equal("1"))
+			Expect(c.Acknowledge.Uuid).To(Equal(statusUpdate.Update.Status.Uuid))
+			push.OK()
+		}()
+
+		Expect(upd.Ack(ctx)).To(Succeed())
+		Expect(acks.Pending()).To(BeEmpty())
+		Expect(monitor.acked).To(Equal(1))
+		Expect(upd.Ack(ctx)).To(Equal(ErrNotPending))
+
+		close(done)
+	})
+
+	It("Keep update pending when explicit ack failed", func(done Done) {
+		acks := New(WithExplicitAcks(0))
+		sink := flow.NewTestFlow()
+		acks.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(statusUpdate)
+			sink.ExpectPush().Error(errors.New("boom"))
+			sink.ExpectPush().OK()
+		}()
+
+		_, err := acks.Pull(ctx)
+		Expect(err).To(Succeed())
+		msg, err := acks.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		upd := msg.(*Update)
+		Expect(upd.Ack(ctx)).To(HaveOccurred())
+		Expect(acks.Pending()).To(HaveLen(1))
+		Expect(upd.Ack(ctx)).To(Succeed())
+		Expect(acks.Pending()).To(BeEmpty())
+
+		close(done)
+	})
+
+	It("Expose update event to stages placed before explicit ack stage", func(done Done) {
+		acks := New(WithExplicitAcks(0))
+		sink := flow.NewTestFlow()
+		acks.Via(sink)
+		store := scheduler.NewMemoryTaskStore()
+		tracker := tasks.New(store)
+		tracker.Via(acks)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(statusUpdate)
+		}()
+
+		_, err := tracker.Pull(ctx)
+		Expect(err).To(Succeed())
+		msg, err := tracker.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		_, ok := msg.(*Update)
+		Expect(ok).To(BeTrue())
+		e, ok := scheduler.EventOf(msg)
+		Expect(ok).To(BeTrue())
+		Expect(scheduler.IsUpdate(e)).To(BeTrue())
+
+		t, ok := store.Get("1")
+		Expect(ok).To(BeTrue())
+		Expect(t.GetStatus()).To(Equal(statusUpdate.Update.Status))
+
+		close(done)
+	})
 })
+
+type testMonitor struct {
+	acked   int
+	failed  int
+	unacked chan *mesos.TaskStatus
+}
+
+func (t *testMonitor) Acked(time.Duration) {
+	t.acked++
+}
+
+func (t *testMonitor) AckFailed(error) {
+	t.failed++
+}
+
+func (t *testMonitor) Unacked(status *mesos.TaskStatus, age time.Duration) {
+	t.unacked <- status
+}
@@ -0,0 +1,192 @@
+package correlate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/message"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+// Returned by Request when no response was received within timeout
+var ErrTimeout = errors.New("Correlate: response timeout")
+
+type (
+	Opt func(c *Correlator)
+
+	Correlator struct {
+		timeout time.Duration
+		// correlation id -> waiting request
+		pending map[string]chan *message.Envelope
+		// framework id from subscribed event, set on request calls
+		frameworkId string
+
+		via flow.Flow
+		log log.Logger
+		sync.Mutex
+	}
+)
+
+const DEFAULT_TIMEOUT = 10 * time.Second
+
+// How long Request waits for response, 0 means until request context is done
+func WithTimeout(d time.Duration) Opt {
+	if d < 0 {
+		panic(fmt.Sprintf("Timeout must be >= 0, is %v", d))
+	}
+
+	return func(c *Correlator) {
+		c.timeout = d
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Correlator) {
+		c.log = l
+	}
+}
+
+func Blueprint(opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "correlate_stage")))
+		}
+		return New(opts...)
+	})
+}
+
+// Matches executor responses (message events with envelope replying to request) to requests sent using Request.
+// Requests are sent with framework id from subscribed event.
+// Matched responses are not returned by Pull, all other messages (including late responses) are passed through.
+// Responses are received only while application keeps pulling.
+func New(opts ...Opt) *Correlator {
+	c := &Correlator{
+		timeout: DEFAULT_TIMEOUT,
+		pending: make(map[string]chan *message.Envelope),
+		log:     log.NewNopLogger(),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+var _ = flow.Stage(&Correlator{})
+
+// Sends request to executor and waits for response.
+// Correlation id is generated when request does not have one.
+func (c *Correlator) Request(ctx context.Context, agentId, executorId string, req *message.Envelope) (*message.Envelope, error) {
+	if req.GetCorrelationId() == "" {
+		message.WithCorrelationId(message.NewCorrelationId())(req)
+	}
+	id := req.GetCorrelationId()
+
+	call, err := message.SchedulerCall(agentId, executorId, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make(chan *message.Envelope, 1)
+
+	c.Lock()
+	if _, exists := c.pending[id]; exists {
+		c.Unlock()
+		return nil, errors.Errorf("Correlate: request %v already pending", id)
+	}
+	c.pending[id] = resp
+	call.With(scheduler.FrameworkId(c.frameworkId))
+	c.Unlock()
+
+	defer func() {
+		c.Lock()
+		delete(c.pending, id)
+		c.Unlock()
+	}()
+
+	if err := c.via.Push(call, ctx); err != nil {
+		return nil, err
+	}
+
+	var timeout <-chan time.Time
+	if c.timeout > 0 {
+		t := time.NewTimer(c.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case r := <-resp:
+		return r, nil
+	case <-timeout:
+		return nil, errors.Wrapf(ErrTimeout, "request %v", id)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Correlator) Push(ev flow.Message, ctx context.Context) error {
+	return c.via.Push(ev, ctx)
+}
+
+func (c *Correlator) Pull(ctx context.Context) (flow.Message, error) {
+	for {
+		ev, err := c.via.Pull(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, e := scheduler.IsSubscribedMessage(ev); ok {
+			c.Lock()
+			c.frameworkId = e.GetSubscribed().GetFrameworkId().GetValue()
+			c.Unlock()
+		}
+
+		if !c.response(ev) {
+			return ev, nil
+		}
+	}
+}
+
+func (c *Correlator) Via(f flow.Flow) {
+	c.via = f
+}
+
+func (c *Correlator) Close() error {
+	return c.via.Close()
+}
+
+// Returns true when message was response to pending request
+func (c *Correlator) response(ev flow.Message) bool {
+	is, e := scheduler.IsMessage(ev)
+	if !is {
+		return false
+	}
+
+	env, err := message.FromSchedulerEvent(e)
+	if err != nil || env.GetReplyTo() == "" {
+		return false
+	}
+
+	c.Lock()
+	resp, ok := c.pending[env.GetReplyTo()]
+	if ok {
+		delete(c.pending, env.GetReplyTo())
+	}
+	c.Unlock()
+
+	if !ok {
+		c.log.Log("event", "unexpected_response", "reply_to", env.GetReplyTo())
+		return false
+	}
+
+	resp <- env
+	return true
+}
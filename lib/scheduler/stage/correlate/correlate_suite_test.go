@@ -0,0 +1,109 @@
+package correlate_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/correlate"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/message"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+func TestCorrelate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Correlate stage suite")
+}
+
+var _ = Describe("Correlate stage", func() {
+
+	messageEvent := func(env *message.Envelope) *scheduler.Event {
+		call, err := message.SchedulerCall("agent", "executor", env)
+		Expect(err).To(Succeed())
+		return &scheduler.Event{
+			Type:    scheduler.Event_MESSAGE.Enum(),
+			Message: &scheduler.Event_Message{AgentId: call.Message.AgentId, ExecutorId: call.Message.ExecutorId, Data: call.Message.Data},
+		}
+	}
+
+	It("Match response to request", func(done Done) {
+		c := New()
+		sink := flow.NewTestFlow()
+		c.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			push := sink.ExpectPush()
+			call := push.Msg.(*scheduler.Call)
+			Expect(call.GetType()).To(Equal(scheduler.Call_MESSAGE))
+			Expect(call.FrameworkId.GetValue()).To(Equal("1"))
+			req, err := message.Unmarshal(call.Message.Data)
+			Expect(err).To(Succeed())
+			Expect(req.GetCorrelationId()).NotTo(BeEmpty())
+			push.OK()
+
+			other, err := message.New(&mesos.TaskID{Value: mesos.Strp("other")})
+			Expect(err).To(Succeed())
+			resp, err := message.New(&mesos.TaskID{Value: mesos.Strp("response")}, message.WithReplyTo(req))
+			Expect(err).To(Succeed())
+
+			sink.ExpectPull().Message(messageEvent(resp))
+			sink.ExpectPull().Message(messageEvent(other))
+		}()
+
+		_, err := c.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		pulled := make(chan flow.Message, 1)
+		go func() {
+			defer GinkgoRecover()
+			msg, err := c.Pull(ctx)
+			Expect(err).To(Succeed())
+			pulled <- msg
+		}()
+
+		req, err := message.New(&mesos.TaskID{Value: mesos.Strp("request")})
+		Expect(err).To(Succeed())
+		resp, err := c.Request(ctx, "agent", "executor", req)
+		Expect(err).To(Succeed())
+
+		decoded := &mesos.TaskID{}
+		Expect(resp.Decode(decoded)).To(Succeed())
+		Expect(decoded.GetValue()).To(Equal("response"))
+
+		var msg flow.Message
+		Eventually(pulled).Should(Receive(&msg))
+		env, err := message.FromSchedulerEvent(msg.(*scheduler.Event))
+		Expect(err).To(Succeed())
+		Expect(env.Decode(decoded)).To(Succeed())
+		Expect(decoded.GetValue()).To(Equal("other"))
+
+		close(done)
+	})
+
+	It("Fail request without response within timeout", func(done Done) {
+		c := New(WithTimeout(10 * time.Millisecond))
+		sink := flow.NewTestFlow()
+		c.Via(sink)
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPush().OK()
+		}()
+
+		req, err := message.New(&mesos.TaskID{Value: mesos.Strp("request")})
+		Expect(err).To(Succeed())
+		_, err = c.Request(context.Background(), "agent", "executor", req)
+		Expect(errors.Cause(err)).To(Equal(ErrTimeout))
+
+		close(done)
+	})
+})
@@ -6,7 +6,9 @@ import (
 
 	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
 )
 
 type (
@@ -15,6 +17,10 @@ type (
 	FwId struct {
 		via         flow.Flow
 		frameworkId string
+		storage     Storage
+		// last id known to be in storage
+		stored string
+		log    log.Logger
 		sync.RWMutex
 	}
 )
@@ -25,15 +31,34 @@ func WithFrameworkId(id string) Opt {
 	}
 }
 
+// Framework id is loaded from storage before subscribe (unless already known) and stored after subscribed
+func WithStorage(s Storage) Opt {
+	return func(c *FwId) {
+		c.storage = s
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *FwId) {
+		c.log = l
+	}
+}
+
 func Blueprint(opts ...Opt) flow.StageBlueprint {
 	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "fwid_stage")))
+		}
 		return New(opts...)
 	})
 }
 
-// Sets framework id from subscribe call on all following calls
+// Sets framework id from subscribe call on all following calls.
+// With storage framework id is persisted so restarted scheduler subscribes as the same framework,
+// stored id is cleared when Mesos reports that framework has been removed.
 func New(opts ...Opt) flow.Stage {
-	cfg := &FwId{}
+	cfg := &FwId{log: log.NewNopLogger()}
 	for _, o := range opts {
 		o(cfg)
 	}
@@ -41,6 +66,12 @@ func New(opts ...Opt) flow.Stage {
 }
 
 func (h *FwId) Push(ev flow.Message, ctx context.Context) error {
+	if is, _ := scheduler.IsSubscribeMessage(ev); is {
+		if err := h.load(); err != nil {
+			return err
+		}
+	}
+
 	h.RLock()
 	fwId := h.frameworkId
 	h.RUnlock()
@@ -71,11 +102,14 @@ func (h *FwId) Pull(ctx context.Context) (flow.Message, error) {
 	if msg, err := h.via.Pull(ctx); err != nil {
 		return msg, err
 	} else {
-		is, e := scheduler.IsSubscribedMessage(msg)
-		if is {
-			h.Lock()
-			h.frameworkId = e.Subscribed.FrameworkId.GetValue()
-			h.Unlock()
+		if is, e := scheduler.IsSubscribedMessage(msg); is {
+			if err := h.subscribed(e.Subscribed.FrameworkId.GetValue()); err != nil {
+				return nil, err
+			}
+		} else if e, ok := scheduler.EventOf(msg); ok && e.GetType() == scheduler.Event_ERROR {
+			if err := h.error(e.GetError().GetMessage()); err != nil {
+				return nil, err
+			}
 		}
 		return msg, err
 	}
@@ -88,3 +122,66 @@ func (h *FwId) Via(f flow.Flow) {
 func (h *FwId) Close() error {
 	return h.via.Close()
 }
+
+func (h *FwId) load() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.storage == nil || h.frameworkId != "" {
+		return nil
+	}
+
+	id, err := h.storage.Load()
+	if err != nil {
+		return errors.Wrap(err, "Framework id stage: load")
+	}
+
+	if id != "" {
+		h.log.Log("event", "loaded", "framework_id", id)
+	}
+
+	h.frameworkId = id
+	h.stored = id
+
+	return nil
+}
+
+func (h *FwId) subscribed(id string) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.frameworkId = id
+	if h.storage == nil || h.stored == id {
+		return nil
+	}
+
+	if err := h.storage.Store(id); err != nil {
+		return errors.Wrap(err, "Framework id stage: store")
+	}
+
+	h.log.Log("event", "stored", "framework_id", id)
+	h.stored = id
+
+	return nil
+}
+
+func (h *FwId) error(msg string) error {
+	if !scheduler.IsFrameworkRemovedMessage(msg) {
+		return nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.log.Log("event", "framework_removed", "framework_id", h.frameworkId)
+	h.frameworkId = ""
+	h.stored = ""
+
+	if h.storage != nil {
+		if err := h.storage.Clear(); err != nil {
+			return errors.Wrap(err, "Framework id stage: clear")
+		}
+	}
+
+	return nil
+}
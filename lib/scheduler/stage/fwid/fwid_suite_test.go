@@ -6,8 +6,12 @@ import (
 	. "github.com/onsi/gomega"
 
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
 )
@@ -67,4 +71,84 @@ var _ = Describe("FrameworkId stage", func() {
 
 		close(done)
 	})
+
+	It("Load framework id from storage and store subscribed id", func(done Done) {
+		storage := NewMemoryStorage()
+		Expect(storage.Store("5")).To(Succeed())
+
+		fwId := New(WithStorage(storage))
+		sink := flow.NewTestFlow()
+		fwId.Via(sink)
+
+		go func() {
+			defer GinkgoRecover()
+
+			push := sink.ExpectPush()
+			c, ok := push.Msg.(*scheduler.Call)
+			Expect(ok).To(BeTrue())
+			Expect(c.FrameworkId.GetValue()).To(Equal("5"))
+			Expect(c.Subscribe.FrameworkInfo.Id.GetValue()).To(Equal("5"))
+			push.OK()
+
+			sink.ExpectPull().Message(scheduler.TestSubscribed("6"))
+		}()
+
+		Expect(fwId.Push(scheduler.Subscribe(scheduler.TestFrameworkInfo()), context.Background())).To(Succeed())
+		_, err := fwId.Pull(context.Background())
+		Expect(err).To(Succeed())
+		Expect(storage.Load()).To(Equal("6"))
+
+		close(done)
+	})
+
+	It("Clear stored framework id when framework is removed", func(done Done) {
+		storage := NewMemoryStorage()
+		fwId := New(WithStorage(storage))
+		sink := flow.NewTestFlow()
+		fwId.Via(sink)
+
+		go func() {
+			defer GinkgoRecover()
+
+			sink.ExpectPull().Message(scheduler.TestSubscribed("5"))
+			sink.ExpectPull().Message(&scheduler.Event{
+				Type:  scheduler.Event_ERROR.Enum(),
+				Error: &scheduler.Event_Error{Message: mesos.Strp("Framework has been removed")},
+			})
+
+			push := sink.ExpectPush()
+			c, ok := push.Msg.(*scheduler.Call)
+			Expect(ok).To(BeTrue())
+			Expect(c.FrameworkId).To(BeNil())
+			Expect(c.Subscribe.FrameworkInfo.Id).To(BeNil())
+			push.OK()
+		}()
+
+		_, err := fwId.Pull(context.Background())
+		Expect(err).To(Succeed())
+		Expect(storage.Load()).To(Equal("5"))
+
+		_, err = fwId.Pull(context.Background())
+		Expect(err).To(Succeed())
+		Expect(storage.Load()).To(BeEmpty())
+
+		Expect(fwId.Push(scheduler.Subscribe(scheduler.TestFrameworkInfo()), context.Background())).To(Succeed())
+
+		close(done)
+	})
+
+	It("File storage", func() {
+		dir, err := ioutil.TempDir("", "fwid")
+		Expect(err).To(Succeed())
+		defer os.RemoveAll(dir)
+
+		storage := NewFileStorage(filepath.Join(dir, "framework_id"))
+		Expect(storage.Load()).To(BeEmpty())
+		Expect(storage.Store("5")).To(Succeed())
+		Expect(storage.Store("6")).To(Succeed())
+		Expect(NewFileStorage(filepath.Join(dir, "framework_id")).Load()).To(Equal("6"))
+		Expect(storage.Clear()).To(Succeed())
+		Expect(storage.Clear()).To(Succeed())
+		Expect(storage.Load()).To(BeEmpty())
+	})
 })
@@ -0,0 +1,152 @@
+package fwid
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Persists framework id between scheduler restarts, implementations must be safe for concurrent use
+	Storage interface {
+		// Returns stored framework id, empty string when no id is stored
+		Load() (string, error)
+		Store(id string) error
+		Clear() error
+	}
+
+	// Key-value store (e.g. etcd client adapter)
+	KV interface {
+		// Returns false when key does not exist
+		Get(key string) (string, bool, error)
+		Put(key, value string) error
+		// Deleting missing key is not an error
+		Delete(key string) error
+	}
+
+	// Stores framework id in file
+	FileStorage struct {
+		path string
+		sync.Mutex
+	}
+
+	// Stores framework id under single key
+	KVStorage struct {
+		kv  KV
+		key string
+	}
+
+	// Keeps framework id in memory only, useful for tests
+	MemoryStorage struct {
+		id string
+		sync.Mutex
+	}
+)
+
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{path: path}
+}
+
+var _ = Storage(&FileStorage{})
+
+func (f *FileStorage) Load() (string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrap(err, "FileStorage: load")
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Writes id to temporary file and renames it, so file always contains complete id
+func (f *FileStorage) Store(id string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path))
+	if err != nil {
+		return errors.Wrap(err, "FileStorage: store")
+	}
+
+	if _, err = tmp.WriteString(id); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "FileStorage: store")
+	}
+
+	return nil
+}
+
+func (f *FileStorage) Clear() error {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "FileStorage: clear")
+	}
+	return nil
+}
+
+func NewKVStorage(kv KV, key string) *KVStorage {
+	return &KVStorage{kv: kv, key: key}
+}
+
+var _ = Storage(&KVStorage{})
+
+func (k *KVStorage) Load() (string, error) {
+	v, ok, err := k.kv.Get(k.key)
+	if err != nil {
+		return "", errors.Wrap(err, "KVStorage: load")
+	} else if !ok {
+		return "", nil
+	}
+	return v, nil
+}
+
+func (k *KVStorage) Store(id string) error {
+	return errors.Wrap(k.kv.Put(k.key, id), "KVStorage: store")
+}
+
+func (k *KVStorage) Clear() error {
+	return errors.Wrap(k.kv.Delete(k.key), "KVStorage: clear")
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+var _ = Storage(&MemoryStorage{})
+
+func (m *MemoryStorage) Load() (string, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.id, nil
+}
+
+func (m *MemoryStorage) Store(id string) error {
+	m.Lock()
+	m.id = id
+	m.Unlock()
+	return nil
+}
+
+func (m *MemoryStorage) Clear() error {
+	return m.Store("")
+}
@@ -3,6 +3,7 @@ package heartbeat
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
@@ -16,13 +17,39 @@ type (
 	Heartbeats struct {
 		maxMissed         int64
 		heartbeatDeadline *time.Duration
+		// heartbeat interval from subscribed event (0 when unknown)
+		interval time.Duration
+		// time of last received message
+		last time.Time
+		// time reading was resumed after queue was full
+		resumed time.Time
+
+		// messages received from next flow but not yet pulled
+		queue     []flow.Message
+		queueSize int
+		// reading is paused because queue is full
+		paused bool
+		// closed and replaced on every change of queue or err
+		changed chan struct{}
+		// terminal error (pull failure or missed heartbeats)
+		err error
+
+		started bool
+		closed  bool
 
 		via flow.Flow
 		log log.Logger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		sync.Mutex
 	}
 )
 
-const DEFAULT_MAX_MISSED = 1
+const (
+	DEFAULT_MAX_MISSED = 1
+	DEFAULT_QUEUE_SIZE = 16
+)
 
 func WithMaxMissedHeartbeats(max uint64) Opt {
 	return func(c *Heartbeats) {
@@ -30,12 +57,24 @@ func WithMaxMissedHeartbeats(max uint64) Opt {
 	}
 }
 
+// Maximum number of messages read ahead of pull calls
+func WithQueueSize(size int) Opt {
+	if size <= 0 {
+		panic(fmt.Sprintf("Queue size must be > 0, is %v", size))
+	}
+
+	return func(c *Heartbeats) {
+		c.queueSize = size
+	}
+}
+
 func WithLogger(l log.Logger) Opt {
 	return func(c *Heartbeats) {
 		c.log = l
 	}
 }
 
+// Deadline used until heartbeat interval is received in subscribed event
 func WithHeartbeatDeadline(d time.Duration) Opt {
 	if d <= 0 {
 		panic(fmt.Sprintf("Deadline must be > 0, is %v", d))
@@ -57,13 +96,23 @@ func Blueprint(opts ...Opt) flow.StageBlueprint {
 	})
 }
 
-// Set deadline for pull request based on configuration.
-// Also sets initial deadline for subscribe call.
-// When no deadline is configured - it is set from subscribed event.
+// Watches arrival of messages independently of pull calls.
+// After first pull, messages are read from next flow in background and queued until pulled.
+// When queue is full, reading is paused (next flow buffers or applies its overflow policy)
+// and deadline is not checked until application pulls.
+// Deadline is set from configuration until subscribed event with heartbeat interval is received,
+// then it is interval * (max missed + 1) and time of last message is reset.
+// When no message arrives within deadline, next flow is closed and Pull returns scheduler.ErrHeartbeatMissed.
 func New(opts ...Opt) *Heartbeats {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	h := &Heartbeats{
 		maxMissed: DEFAULT_MAX_MISSED,
+		queueSize: DEFAULT_QUEUE_SIZE,
+		changed:   make(chan struct{}),
 		log:       log.NewNopLogger(),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
 	for _, o := range opts {
@@ -75,46 +124,221 @@ func New(opts ...Opt) *Heartbeats {
 
 var _ = flow.Stage(&Heartbeats{})
 
+// Returns time of last received message, zero when nothing was received
+func (h *Heartbeats) LastHeartbeat() time.Time {
+	h.Lock()
+	defer h.Unlock()
+	return h.last
+}
+
+// Returns number of heartbeat intervals elapsed since last received message (0 when interval is not known)
+func (h *Heartbeats) Missed() int {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.interval == 0 || h.last.IsZero() {
+		return 0
+	}
+	return int(time.Now().Sub(h.last) / h.interval)
+}
+
 func (h *Heartbeats) Push(ev flow.Message, ctx context.Context) error {
 	return h.via.Push(ev, ctx)
 }
 
 func (h *Heartbeats) Pull(ctx context.Context) (flow.Message, error) {
+	h.start()
 
-	var deadlineCtx context.Context
+	for {
+		h.Lock()
+		// messages received before failure are pulled first
+		if len(h.queue) > 0 {
+			ev := h.queue[0]
+			h.queue[0] = nil
+			h.queue = h.queue[1:]
+			h.signal()
+			h.Unlock()
+			return ev, nil
+		}
 
-	if h.heartbeatDeadline != nil {
-		c, cancel := context.WithTimeout(ctx, *h.heartbeatDeadline)
-		deadlineCtx = c
-		defer cancel()
-	} else {
-		deadlineCtx = ctx
+		if h.err != nil {
+			err := h.err
+			h.Unlock()
+			return nil, err
+		}
+		changed := h.changed
+		h.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 	}
+}
 
-	ev, err := h.via.Pull(deadlineCtx)
+func (h *Heartbeats) Via(f flow.Flow) {
+	h.via = f
+}
 
-	if err == nil {
-		switch e := ev.(type) {
-		case *scheduler.Event:
-			if scheduler.IsSubscribed(e) {
-				if e.Subscribed.HeartbeatIntervalSeconds != nil {
-					// use precision up to milliseconds
-					tmp := int64(e.Subscribed.GetHeartbeatIntervalSeconds()*1000) * (h.maxMissed + 1)
-					deadline := time.Duration(tmp) * time.Millisecond
-					h.log.Log("event", "heartbeat_set", "deadline", deadline)
-					h.heartbeatDeadline = &deadline
-				}
+func (h *Heartbeats) Close() error {
+	h.cancel()
+	return h.closeVia()
+}
+
+func (h *Heartbeats) start() {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.started {
+		return
+	}
+
+	h.started = true
+	h.last = time.Now()
+
+	go h.read()
+	go h.watch()
+}
+
+func (h *Heartbeats) read() {
+	for {
+		if !h.waitForRoom() {
+			return
+		}
+
+		ev, err := h.via.Pull(h.ctx)
+
+		h.Lock()
+		if err != nil {
+			if h.err == nil {
+				h.err = err
 			}
+			h.signal()
+			h.Unlock()
+			return
+		}
+
+		h.last = time.Now()
+		if is, e := scheduler.IsSubscribedMessage(ev); is && e.Subscribed.HeartbeatIntervalSeconds != nil {
+			// use precision up to milliseconds
+			h.interval = time.Duration(int64(e.Subscribed.GetHeartbeatIntervalSeconds()*1000)) * time.Millisecond
+			deadline := h.interval * time.Duration(h.maxMissed+1)
+			h.log.Log("event", "heartbeat_set", "deadline", deadline)
+			h.heartbeatDeadline = &deadline
 		}
+
+		h.queue = append(h.queue, ev)
+		h.signal()
+		h.Unlock()
 	}
+}
+
+// Waits until queue is not full, returns false when stage was closed
+func (h *Heartbeats) waitForRoom() bool {
+	h.Lock()
+	defer h.Unlock()
+
+	for len(h.queue) >= h.queueSize {
+		if !h.paused {
+			h.paused = true
+			h.signal()
+		}
+		changed := h.changed
+		h.Unlock()
+
+		select {
+		case <-changed:
+		case <-h.ctx.Done():
+		}
 
-	return ev, err
+		h.Lock()
+		if h.ctx.Err() != nil {
+			if h.err == nil {
+				h.err = h.ctx.Err()
+			}
+			h.signal()
+			return false
+		}
+	}
+
+	if h.paused {
+		h.paused = false
+		h.resumed = time.Now()
+		h.signal()
+	}
+
+	return true
 }
 
-func (h *Heartbeats) Via(f flow.Flow) {
-	h.via = f
+func (h *Heartbeats) watch() {
+	for {
+		h.Lock()
+		if h.err != nil || h.ctx.Err() != nil {
+			h.Unlock()
+			return
+		}
+
+		wait := time.Duration(-1)
+		// time spent with full queue is not counted
+		if h.heartbeatDeadline != nil && !h.paused {
+			from := h.last
+			if h.resumed.After(from) {
+				from = h.resumed
+			}
+			wait = from.Add(*h.heartbeatDeadline).Sub(time.Now())
+			if wait <= 0 {
+				missed := scheduler.ErrHeartbeatMissed{Missed: 1, Interval: *h.heartbeatDeadline, LastHeartbeat: h.last}
+				if h.interval > 0 {
+					missed.Missed = int(time.Now().Sub(h.last) / h.interval)
+					missed.Interval = h.interval
+				}
+				h.log.Log("event", "heartbeat_missed", "err", missed)
+				h.err = missed
+				h.signal()
+				h.Unlock()
+
+				h.closeVia()
+				return
+			}
+		}
+		changed := h.changed
+		h.Unlock()
+
+		// without deadline (not yet known or paused) wait for change
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-timeout:
+		case <-changed:
+		case <-h.ctx.Done():
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
 }
 
-func (h *Heartbeats) Close() error {
+func (h *Heartbeats) closeVia() error {
+	h.Lock()
+	if h.closed {
+		h.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.Unlock()
+
 	return h.via.Close()
 }
+
+// must be called with lock held
+func (h *Heartbeats) signal() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
 )
 
 func TestAck(t *testing.T) {
@@ -18,83 +19,175 @@ func TestAck(t *testing.T) {
 	RunSpecs(t, "Heartbeat stage suite")
 }
 
-var _ = Describe("FrameworkId stage", func() {
+var _ = Describe("Heartbeat stage", func() {
 
 	ptoFloat := func(f float64) *float64 { return &f }
 
-	It("Set deadling from subscribed event", func(done Done) {
-		fwId := New(WithMaxMissedHeartbeats(1))
+	// accepts close of stalled flow and fails its pending pull
+	expectClose := func(sink *flow.TestFlow, closed chan struct{}) {
+		sink.AcceptClose().OK()
+		close(closed)
+		sink.ExpectPull().Error(errors.New("closed"))
+	}
+
+	It("Set deadline from subscribed event", func(done Done) {
+		hb := New(WithMaxMissedHeartbeats(1))
 		sink := flow.NewTestFlow()
-		fwId.Via(sink)
+		hb.Via(sink)
+		closed := make(chan struct{})
 
 		go func() {
 			defer GinkgoRecover()
-			id := "5"
-
-			pull := sink.ExpectPull()
-			s := scheduler.TestSubscribed(id)
+			s := scheduler.TestSubscribed("5")
 			s.Subscribed.HeartbeatIntervalSeconds = ptoFloat(0.01) // 10 millis
-			pull.Message(s)
-
-			// this pull times out
-			pull = sink.ExpectPull()
-			start := time.Now()
-			<-pull.Ctx.Done()
-			Expect(time.Now().Sub(start)).To(BeNumerically(">=", 20*time.Millisecond, 10*time.Millisecond))
-			pull.Error(pull.Ctx.Err())
-
-			// this pull does not
-			pull = sink.ExpectPull()
-			time.Sleep(5 * time.Millisecond)
-			pull.Message(&scheduler.Event{})
+			sink.ExpectPull().Message(s)
+			expectClose(sink, closed)
 		}()
 
-		_, err := fwId.Pull(context.Background())
+		_, err := hb.Pull(context.Background())
 		Expect(err).To(Succeed())
+		start := time.Now()
+
+		_, err = hb.Pull(context.Background())
+		Expect(time.Now().Sub(start)).To(BeNumerically(">=", 15*time.Millisecond))
+		missed, details := scheduler.IsHeartbeatMissed(err)
+		Expect(missed).To(BeTrue())
+		Expect(details.Missed).To(BeNumerically(">=", 2))
+		Expect(details.Interval).To(Equal(10 * time.Millisecond))
+		Expect(details.LastHeartbeat.IsZero()).To(BeFalse())
+		Expect(hb.LastHeartbeat()).To(Equal(details.LastHeartbeat))
+		Eventually(closed).Should(BeClosed())
+
+		close(done)
+	})
+
+	It("Close stalled flow when application does not pull", func(done Done) {
+		hb := New(WithHeartbeatDeadline(20 * time.Millisecond))
+		sink := flow.NewTestFlow()
+		hb.Via(sink)
+		closed := make(chan struct{})
 
-		_, err = fwId.Pull(context.Background())
-		Expect(err).To(Equal(context.DeadlineExceeded))
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestHeartbeat())
+			expectClose(sink, closed)
+		}()
 
-		_, err = fwId.Pull(context.Background())
+		_, err := hb.Pull(context.Background())
 		Expect(err).To(Succeed())
 
+		Eventually(closed).Should(BeClosed())
+
+		_, err = hb.Pull(context.Background())
+		missed, details := scheduler.IsHeartbeatMissed(err)
+		Expect(missed).To(BeTrue())
+		Expect(details.Missed).To(Equal(1))
+		Expect(details.Interval).To(Equal(20 * time.Millisecond))
+
+		Expect(hb.Close()).To(Succeed())
+
 		close(done)
 	})
 
-	It("Set deadling from configuration", func(done Done) {
-		fwId := New(WithMaxMissedHeartbeats(3))
+	It("Buffer messages arriving within deadline", func(done Done) {
+		hb := New(WithHeartbeatDeadline(30 * time.Millisecond))
 		sink := flow.NewTestFlow()
-		fwId.Via(sink)
+		hb.Via(sink)
+		received := make(chan struct{})
 
 		go func() {
 			defer GinkgoRecover()
-			id := "5"
-			pull := sink.ExpectPull()
-			s := scheduler.TestSubscribed(id)
-			s.Subscribed.HeartbeatIntervalSeconds = ptoFloat(0.005) // 5 millis - should be IGNORED
-			pull.Message(s)
-
-			// this pull times out
-			pull = sink.ExpectPull()
-			start := time.Now()
-			<-pull.Ctx.Done()
-			Expect(time.Now().Sub(start)).To(BeNumerically(">=", 20*time.Millisecond, 10*time.Millisecond))
-			pull.Error(pull.Ctx.Err())
-
-			// this pull does not
-			pull = sink.ExpectPull()
-			time.Sleep(30 * time.Millisecond)
-			pull.Message(&scheduler.Event{})
+			for i := 0; i < 5; i++ {
+				time.Sleep(10 * time.Millisecond)
+				sink.ExpectPull().Message(scheduler.TestHeartbeat())
+			}
+			close(received)
 		}()
 
-		_, err := fwId.Pull(context.Background())
+		_, err := hb.Pull(context.Background())
 		Expect(err).To(Succeed())
+		Eventually(received).Should(BeClosed())
+
+		for i := 0; i < 4; i++ {
+			_, err := hb.Pull(context.Background())
+			Expect(err).To(Succeed())
+		}
+
+		go func() {
+			defer GinkgoRecover()
+			sink.AcceptClose().OK()
+			sink.ExpectPull().Error(errors.New("closed"))
+		}()
 
-		_, err = fwId.Pull(context.Background())
-		Expect(err).To(Equal(context.DeadlineExceeded))
+		Expect(hb.Close()).To(Succeed())
 
-		_, err = fwId.Pull(context.Background())
+		close(done)
+	})
+
+	It("Stop reading when queue is full", func(done Done) {
+		hb := New(WithHeartbeatDeadline(20*time.Millisecond), WithQueueSize(2))
+		sink := flow.NewTestFlow()
+		hb.Via(sink)
+		paused := make(chan struct{})
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(scheduler.TestHeartbeat())
+			sink.ExpectPull().Message(scheduler.TestHeartbeat())
+			// longer than deadline, missed heartbeat is not reported while paused
+			sink.ExpectNoPull(50 * time.Millisecond)
+			close(paused)
+			sink.ExpectPull().Message(scheduler.TestHeartbeat())
+		}()
+
+		_, err := hb.Pull(context.Background())
 		Expect(err).To(Succeed())
+		<-paused
+
+		for i := 0; i < 3; i++ {
+			msg, err := hb.Pull(context.Background())
+			Expect(err).To(Succeed())
+			Expect(msg).To(Equal(scheduler.TestHeartbeat()))
+		}
+
+		go func() {
+			defer GinkgoRecover()
+			sink.AcceptClose().OK()
+			sink.ExpectPull().Error(errors.New("closed"))
+		}()
+
+		Expect(hb.Close()).To(Succeed())
+
+		close(done)
+	})
+
+	It("Pull received messages before returning error", func(done Done) {
+		hb := New(WithHeartbeatDeadline(time.Second))
+		sink := flow.NewTestFlow()
+		hb.Via(sink)
+		failed := make(chan struct{})
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(scheduler.TestHeartbeat())
+			sink.ExpectPull().Error(errors.New("disconnected"))
+			close(failed)
+		}()
+
+		msg, err := hb.Pull(context.Background())
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(scheduler.TestSubscribed("1")))
+		<-failed
+		time.Sleep(10 * time.Millisecond)
+
+		msg, err = hb.Pull(context.Background())
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(scheduler.TestHeartbeat()))
+
+		_, err = hb.Pull(context.Background())
+		Expect(err).To(MatchError("disconnected"))
 
 		close(done)
 	})
@@ -0,0 +1,354 @@
+package kill
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/backoff"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+// Returned when kill attempts were exhausted without receiving terminal status of task
+var ErrNotKilled = errors.New("Kill: task not confirmed dead")
+
+type (
+	Opt func(c *Killer)
+
+	KillOpt func(k *kill)
+
+	// Outcome of kill, status is set when terminal status update was received
+	Result struct {
+		Status *mesos.TaskStatus
+		Err    error
+	}
+
+	kill struct {
+		taskId     string
+		agentId    string
+		executorId string
+		// executor was shut down
+		shutdown bool
+		waiters  []chan Result
+		done     chan struct{}
+		finished bool
+	}
+
+	Killer struct {
+		backoff       backoff.Provider
+		escalateAfter int
+		gracePeriod   *time.Duration
+
+		kills map[string]*kill
+		// framework id from subscribed event, set on kill and shutdown calls
+		frameworkId string
+
+		via flow.Flow
+		log log.Logger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		sync.Mutex
+	}
+)
+
+const DEFAULT_ESCALATE_AFTER = 5
+
+// Backoff between kill attempts, Result with ErrNotKilled is returned when provider max attempts is reached
+func WithBackoff(p backoff.Provider) Opt {
+	return func(c *Killer) {
+		c.backoff = p
+	}
+}
+
+// Executor of task is shut down after given number of unsuccessful kill attempts, 0 disables escalation
+func WithEscalateAfter(attempts int) Opt {
+	if attempts < 0 {
+		panic(fmt.Sprintf("Attempts must be >= 0, is %v", attempts))
+	}
+
+	return func(c *Killer) {
+		c.escalateAfter = attempts
+	}
+}
+
+// Grace period set as kill policy of all kill calls
+func WithGracePeriod(d time.Duration) Opt {
+	return func(c *Killer) {
+		c.gracePeriod = &d
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Killer) {
+		c.log = l
+	}
+}
+
+// Executor to shut down on escalation (executor id from status updates of task is used otherwise)
+func WithExecutor(executorId string) KillOpt {
+	return func(k *kill) {
+		k.executorId = executorId
+	}
+}
+
+func Blueprint(opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "kill_stage")))
+		}
+		return New(opts...)
+	})
+}
+
+// Kills tasks until terminal status update is received.
+// Kill call is re-sent using backoff (e.g. after agent partition) and when escalation is enabled,
+// executor of task is shut down after configured number of attempts.
+// Calls are sent with framework id from last subscribed event.
+func New(opts ...Opt) *Killer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	k := &Killer{
+		backoff: backoff.New(
+			backoff.Always(),
+			backoff.WithMinWait(5*time.Second),
+			backoff.WithMaxWait(1*time.Minute),
+		),
+		escalateAfter: DEFAULT_ESCALATE_AFTER,
+		kills:         make(map[string]*kill),
+		log:           log.NewNopLogger(),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	for _, o := range opts {
+		o(k)
+	}
+
+	return k
+}
+
+var _ = flow.Stage(&Killer{})
+
+// Starts killing task, returned channel receives single result when task is confirmed dead,
+// attempts are exhausted or ctx is done. Killing task that is already being killed joins existing kill.
+func (k *Killer) Kill(ctx context.Context, taskId, agentId string, opts ...KillOpt) <-chan Result {
+	res := make(chan Result, 1)
+
+	k.Lock()
+	if kl, ok := k.kills[taskId]; ok {
+		kl.waiters = append(kl.waiters, res)
+		for _, o := range opts {
+			o(kl)
+		}
+		k.Unlock()
+
+		go k.wait(ctx, kl, res)
+		return res
+	}
+
+	kl := &kill{
+		taskId:  taskId,
+		agentId: agentId,
+		waiters: []chan Result{res},
+		done:    make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(kl)
+	}
+	k.kills[taskId] = kl
+	k.Unlock()
+
+	k.log.Log("event", "kill_started", "task", taskId, "agent", agentId)
+
+	go k.wait(ctx, kl, res)
+	go k.run(kl)
+
+	return res
+}
+
+// Returns ids of tasks being killed
+func (k *Killer) Pending() []string {
+	k.Lock()
+	defer k.Unlock()
+
+	res := make([]string, 0, len(k.kills))
+	for id := range k.kills {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (k *Killer) Push(ev flow.Message, ctx context.Context) error {
+	return k.via.Push(ev, ctx)
+}
+
+func (k *Killer) Pull(ctx context.Context) (flow.Message, error) {
+	ev, err := k.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := scheduler.EventOf(ev); ok {
+		if scheduler.IsSubscribed(e) {
+			k.Lock()
+			k.frameworkId = e.GetSubscribed().GetFrameworkId().GetValue()
+			k.Unlock()
+		} else if scheduler.IsUpdate(e) {
+			k.update(e.GetUpdate().GetStatus())
+		}
+	}
+
+	return ev, nil
+}
+
+func (k *Killer) Via(f flow.Flow) {
+	k.via = f
+}
+
+func (k *Killer) Close() error {
+	k.cancel()
+
+	k.Lock()
+	for _, kl := range k.kills {
+		k.finish(kl, Result{Err: context.Canceled})
+	}
+	k.Unlock()
+
+	return k.via.Close()
+}
+
+func (k *Killer) update(status *mesos.TaskStatus) {
+	k.Lock()
+	defer k.Unlock()
+
+	kl, ok := k.kills[status.GetTaskId().GetValue()]
+	if !ok {
+		return
+	}
+
+	if id := status.GetExecutorId().GetValue(); id != "" && kl.executorId == "" {
+		kl.executorId = id
+	}
+	if id := status.GetAgentId().GetValue(); id != "" {
+		kl.agentId = id
+	}
+
+	if mesos.IsTerminalState(status.GetState()) {
+		k.log.Log("event", "killed", "task", kl.taskId, "state", status.GetState().String())
+		k.finish(kl, Result{Status: status})
+	}
+}
+
+// must be called with lock held
+func (k *Killer) finish(kl *kill, res Result) {
+	if kl.finished {
+		return
+	}
+
+	kl.finished = true
+	close(kl.done)
+	delete(k.kills, kl.taskId)
+
+	for _, w := range kl.waiters {
+		w <- res
+	}
+}
+
+// Delivers ctx error to waiter when ctx is done before kill finished
+func (k *Killer) wait(ctx context.Context, kl *kill, res chan Result) {
+	select {
+	case <-kl.done:
+	case <-ctx.Done():
+		k.Lock()
+		defer k.Unlock()
+
+		if kl.finished {
+			return
+		}
+
+		for i, w := range kl.waiters {
+			if w == res {
+				kl.waiters = append(kl.waiters[:i], kl.waiters[i+1:]...)
+				break
+			}
+		}
+		res <- Result{Err: ctx.Err()}
+
+		if len(kl.waiters) == 0 {
+			// nobody is interested anymore
+			kl.finished = true
+			close(kl.done)
+			delete(k.kills, kl.taskId)
+		}
+	}
+}
+
+func (k *Killer) run(kl *kill) {
+	retry := k.backoff.New(k.ctx)
+	defer retry.Close()
+
+	for {
+		select {
+		case attempt, ok := <-retry.Attempts():
+			if !ok {
+				k.Lock()
+				if k.ctx.Err() != nil {
+					k.finish(kl, Result{Err: k.ctx.Err()})
+				} else {
+					k.log.Log("event", "kill_failed", "task", kl.taskId, "err", ErrNotKilled)
+					k.finish(kl, Result{Err: errors.Wrapf(ErrNotKilled, "task %v", kl.taskId)})
+				}
+				k.Unlock()
+				return
+			}
+
+			k.attempt(kl, attempt)
+		case <-kl.done:
+			return
+		case <-k.ctx.Done():
+			return
+		}
+	}
+}
+
+func (k *Killer) attempt(kl *kill, attempt int) {
+	k.Lock()
+	taskId, agentId, executorId := kl.taskId, kl.agentId, kl.executorId
+	fwId := scheduler.FrameworkId(k.frameworkId)
+	escalate := k.escalateAfter > 0 && attempt > k.escalateAfter && !kl.shutdown && executorId != "" && agentId != ""
+	k.Unlock()
+
+	if escalate {
+		if err := k.via.Push(scheduler.Shutdown(executorId, agentId).With(fwId), k.ctx); err != nil {
+			k.log.Log("event", "shutdown_failed", "task", taskId, "executor", executorId, "err", err)
+			return
+		}
+
+		k.log.Log("event", "executor_shutdown", "task", taskId, "executor", executorId, "attempt", attempt)
+
+		k.Lock()
+		kl.shutdown = true
+		k.Unlock()
+		return
+	}
+
+	call := scheduler.Kill(taskId, agentId).With(fwId)
+	if k.gracePeriod != nil {
+		call.With(scheduler.KillPolicy(*k.gracePeriod))
+	}
+
+	if err := k.via.Push(call, k.ctx); err != nil {
+		k.log.Log("event", "kill_push_failed", "task", taskId, "attempt", attempt, "err", err)
+	} else if attempt > 1 {
+		k.log.Log("event", "kill_resent", "task", taskId, "attempt", attempt)
+	}
+}
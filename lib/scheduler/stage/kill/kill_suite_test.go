@@ -0,0 +1,167 @@
+package kill_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/kill"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/backoff"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+func TestKill(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Kill stage suite")
+}
+
+var _ = Describe("Kill stage", func() {
+
+	update := func(taskId string, state mesos.TaskState) *scheduler.Event {
+		return &scheduler.Event{
+			Type: scheduler.Event_UPDATE.Enum(),
+			Update: &scheduler.Event_Update{
+				Status: &mesos.TaskStatus{
+					TaskId:  &mesos.TaskID{Value: mesos.Strp(taskId)},
+					AgentId: &mesos.AgentID{Value: mesos.Strp("a1")},
+					State:   state.Enum(),
+				},
+			},
+		}
+	}
+
+	expectCall := func(sink *flow.TestFlow, t scheduler.Call_Type) *scheduler.Call {
+		push := sink.ExpectPush()
+		c, ok := push.Msg.(*scheduler.Call)
+		Expect(ok).To(BeTrue())
+		Expect(c.GetType()).To(Equal(t))
+		push.OK()
+		return c
+	}
+
+	fastBackoff := func(attempts int) Opt {
+		return WithBackoff(backoff.New(
+			backoff.WithMaxAttempts(attempts),
+			backoff.WithMinWait(20*time.Millisecond),
+			backoff.WithMaxWait(10*time.Second),
+			backoff.WithJitterFraction(0),
+		))
+	}
+
+	It("Resolve kill when terminal status is received", func(done Done) {
+		k := New(WithGracePeriod(5 * time.Second))
+		sink := flow.NewTestFlow()
+		k.Via(sink)
+		ctx := context.Background()
+
+		res := k.Kill(ctx, "t1", "a1")
+		c := expectCall(sink, scheduler.Call_KILL)
+		Expect(c.Kill.TaskId.GetValue()).To(Equal("t1"))
+		Expect(c.Kill.AgentId.GetValue()).To(Equal("a1"))
+		Expect(c.Kill.KillPolicy.GetGracePeriod().GetNanoseconds()).To(Equal(int64(5 * time.Second)))
+		Expect(k.Pending()).To(Equal([]string{"t1"}))
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(update("t1", mesos.TaskState_TASK_RUNNING))
+			sink.ExpectPull().Message(update("t1", mesos.TaskState_TASK_KILLED))
+		}()
+
+		_, err := k.Pull(ctx)
+		Expect(err).To(Succeed())
+		Consistently(res).ShouldNot(Receive())
+
+		_, err = k.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		var r Result
+		Eventually(res).Should(Receive(&r))
+		Expect(r.Err).To(Succeed())
+		Expect(r.Status.GetState()).To(Equal(mesos.TaskState_TASK_KILLED))
+		Expect(k.Pending()).To(BeEmpty())
+
+		close(done)
+	})
+
+	It("Re-send kill and shut down executor", func(done Done) {
+		k := New(fastBackoff(10), WithEscalateAfter(2))
+		sink := flow.NewTestFlow()
+		k.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+		}()
+
+		_, err := k.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		// calls are sent with framework id from subscribed event
+		res := k.Kill(ctx, "t1", "a1", WithExecutor("e1"))
+		c := expectCall(sink, scheduler.Call_KILL)
+		Expect(c.GetFrameworkId().GetValue()).To(Equal("1"))
+		expectCall(sink, scheduler.Call_KILL)
+		c = expectCall(sink, scheduler.Call_SHUTDOWN)
+		Expect(c.Shutdown.ExecutorId.GetValue()).To(Equal("e1"))
+		Expect(c.Shutdown.AgentId.GetValue()).To(Equal("a1"))
+		Expect(c.GetFrameworkId().GetValue()).To(Equal("1"))
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(update("t1", mesos.TaskState_TASK_LOST))
+		}()
+
+		_, err = k.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		var r Result
+		Eventually(res).Should(Receive(&r))
+		Expect(r.Status.GetState()).To(Equal(mesos.TaskState_TASK_LOST))
+
+		close(done)
+	})
+
+	It("Fail when attempts are exhausted", func(done Done) {
+		k := New(fastBackoff(2), WithEscalateAfter(0))
+		sink := flow.NewTestFlow()
+		k.Via(sink)
+
+		res := k.Kill(context.Background(), "t1", "a1")
+		expectCall(sink, scheduler.Call_KILL)
+		expectCall(sink, scheduler.Call_KILL)
+
+		var r Result
+		Eventually(res).Should(Receive(&r))
+		Expect(errors.Cause(r.Err)).To(Equal(ErrNotKilled))
+		Expect(k.Pending()).To(BeEmpty())
+
+		close(done)
+	})
+
+	It("Stop waiting when context is done", func(done Done) {
+		k := New(fastBackoff(10))
+		sink := flow.NewTestFlow()
+		k.Via(sink)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		res := k.Kill(ctx, "t1", "a1")
+		expectCall(sink, scheduler.Call_KILL)
+
+		cancel()
+
+		var r Result
+		Eventually(res).Should(Receive(&r))
+		Expect(r.Err).To(Equal(context.Canceled))
+		Eventually(k.Pending).Should(BeEmpty())
+		sink.ExpectNoPush(50 * time.Millisecond)
+
+		close(done)
+	})
+})
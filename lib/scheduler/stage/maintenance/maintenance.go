@@ -0,0 +1,401 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+type (
+	Opt func(c *Maintenance)
+
+	// Decides whether framework released agent (e.g. all tasks were migrated elsewhere)
+	DrainPolicy interface {
+		Drained(agentId string) bool
+	}
+
+	DrainPolicyFunc func(agentId string) bool
+
+	inverseOffer struct {
+		offer    *mesos.InverseOffer
+		received time.Time
+		// orders inverse offers received at the same time
+		seq uint64
+	}
+
+	Maintenance struct {
+		drain         DrainPolicy
+		drainInterval time.Duration
+
+		// outstanding inverse offers by offer id
+		outstanding map[string]*inverseOffer
+		// known unavailability by agent id
+		windows map[string]Window
+		seq     uint64
+		// framework id from subscribed event, set on accept calls of drained agents
+		frameworkId string
+
+		// signals drain check
+		check     chan struct{}
+		cancelRun context.CancelFunc
+
+		via flow.Flow
+		log log.Logger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		sync.Mutex
+	}
+)
+
+const DEFAULT_DRAIN_INTERVAL = 10 * time.Second
+
+func (f DrainPolicyFunc) Drained(agentId string) bool {
+	return f(agentId)
+}
+
+// Agent is drained when store contains no non-terminal task running on it
+func StoreDrained(s scheduler.TaskStore) DrainPolicy {
+	return DrainPolicyFunc(func(agentId string) bool {
+		for _, t := range s.NonTerminal() {
+			if t.GetAgentId() == agentId {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Inverse offers of drained agents are accepted automatically
+func WithDrainPolicy(p DrainPolicy) Opt {
+	return func(c *Maintenance) {
+		c.drain = p
+	}
+}
+
+// How often is drain policy evaluated in addition to evaluation after every status update and inverse offer
+func WithDrainInterval(d time.Duration) Opt {
+	if d <= 0 {
+		panic(fmt.Sprintf("Drain interval must be > 0, is %v", d))
+	}
+
+	return func(c *Maintenance) {
+		c.drainInterval = d
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Maintenance) {
+		c.log = l
+	}
+}
+
+func Blueprint(opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "maintenance_stage")))
+		}
+		return New(opts...)
+	})
+}
+
+// Tracks inverse offers per agent and unavailability windows announced by offers and inverse offers.
+// Inverse offers are removed when rescinded, accepted or declined, all state is forgotten on (re)subscribe.
+// When drain policy is set, inverse offers of agents reported as drained are accepted automatically
+// (with framework id from subscribed event).
+func New(opts ...Opt) *Maintenance {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &Maintenance{
+		drainInterval: DEFAULT_DRAIN_INTERVAL,
+		outstanding:   make(map[string]*inverseOffer),
+		windows:       make(map[string]Window),
+		check:         make(chan struct{}, 1),
+		log:           log.NewNopLogger(),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	for _, o := range opts {
+		o(m)
+	}
+
+	return m
+}
+
+var _ = flow.Stage(&Maintenance{})
+
+// Returns outstanding inverse offers ordered by time of receive
+func (m *Maintenance) InverseOffers() []*mesos.InverseOffer {
+	return m.inverseOffers(func(*mesos.InverseOffer) bool { return true })
+}
+
+// Returns outstanding inverse offers for agent ordered by time of receive
+func (m *Maintenance) AgentInverseOffers(agentId string) []*mesos.InverseOffer {
+	return m.inverseOffers(func(o *mesos.InverseOffer) bool { return o.GetAgentId().GetValue() == agentId })
+}
+
+// Returns known unavailability of agent
+func (m *Maintenance) Unavailability(agentId string) (Window, bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	w, ok := m.windows[agentId]
+	return w, ok
+}
+
+// Returns known unavailability windows ordered by start
+func (m *Maintenance) Windows() []Window {
+	m.Lock()
+	res := make([]Window, 0, len(m.windows))
+	for _, w := range m.windows {
+		res = append(res, w)
+	}
+	m.Unlock()
+
+	sort.Sort(byStart(res))
+	return res
+}
+
+func (m *Maintenance) Push(ev flow.Message, ctx context.Context) error {
+	var used []*inverseOffer
+
+	switch c := ev.(type) {
+	case *scheduler.Call:
+		switch c.GetType() {
+		case scheduler.Call_ACCEPT_INVERSE_OFFERS:
+			used = m.take(c.GetAcceptInverseOffers().GetInverseOfferIds())
+		case scheduler.Call_DECLINE_INVERSE_OFFERS:
+			used = m.take(c.GetDeclineInverseOffers().GetInverseOfferIds())
+		}
+	}
+
+	err := m.via.Push(ev, ctx)
+	if err != nil && len(used) > 0 {
+		// inverse offers are still valid in Mesos
+		m.restore(used)
+	}
+
+	return err
+}
+
+func (m *Maintenance) Pull(ctx context.Context) (flow.Message, error) {
+	ev, err := m.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := scheduler.EventOf(ev); ok {
+		switch e.GetType() {
+		case scheduler.Event_SUBSCRIBED:
+			m.subscribed(e.GetSubscribed().GetFrameworkId().GetValue())
+		case scheduler.Event_OFFERS:
+			m.offers(e.Offers.GetOffers())
+		case scheduler.Event_INVERSE_OFFERS:
+			m.add(e.InverseOffers.GetInverseOffers())
+			m.signal()
+		case scheduler.Event_RESCIND_INVERSE_OFFER:
+			if taken := m.take([]*mesos.OfferID{e.GetRescindInverseOffer().GetInverseOfferId()}); len(taken) > 0 {
+				m.log.Log("event", "rescinded", "inverse_offer", e.GetRescindInverseOffer().GetInverseOfferId().GetValue())
+			}
+		case scheduler.Event_UPDATE:
+			m.signal()
+		}
+	}
+
+	return ev, nil
+}
+
+func (m *Maintenance) Via(f flow.Flow) {
+	m.via = f
+}
+
+func (m *Maintenance) Close() error {
+	m.cancel()
+	return m.via.Close()
+}
+
+func (m *Maintenance) inverseOffers(f func(o *mesos.InverseOffer) bool) []*mesos.InverseOffer {
+	m.Lock()
+	all := make([]*inverseOffer, 0, len(m.outstanding))
+	for _, o := range m.outstanding {
+		if f(o.offer) {
+			all = append(all, o)
+		}
+	}
+	m.Unlock()
+
+	sort.Sort(byReceived(all))
+
+	res := make([]*mesos.InverseOffer, len(all))
+	for i, o := range all {
+		res[i] = o.offer
+	}
+	return res
+}
+
+func (m *Maintenance) subscribed(frameworkId string) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.frameworkId = frameworkId
+	m.outstanding = make(map[string]*inverseOffer)
+	m.windows = make(map[string]Window)
+
+	if m.cancelRun != nil {
+		m.cancelRun()
+	}
+
+	if m.drain != nil {
+		ctx, cancel := context.WithCancel(m.ctx)
+		m.cancelRun = cancel
+		go m.run(ctx)
+	}
+}
+
+// Offer without unavailability means that agent has no maintenance scheduled
+func (m *Maintenance) offers(offers []*mesos.Offer) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, o := range offers {
+		agentId := o.GetAgentId().GetValue()
+		if w, ok := OfferWindow(o); ok {
+			m.windows[agentId] = w
+		} else {
+			delete(m.windows, agentId)
+		}
+	}
+}
+
+func (m *Maintenance) add(offers []*mesos.InverseOffer) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	for _, o := range offers {
+		m.seq += 1
+		m.outstanding[o.GetId().GetValue()] = &inverseOffer{offer: o, received: now, seq: m.seq}
+
+		if agentId := o.GetAgentId().GetValue(); agentId != "" && o.Unavailability != nil {
+			m.windows[agentId] = WindowOf(agentId, o.Unavailability)
+		}
+
+		m.log.Log(
+			"event", "inverse_offer",
+			"inverse_offer", o.GetId().GetValue(),
+			"agent", o.GetAgentId().GetValue(),
+			"start", time.Unix(0, o.GetUnavailability().GetStart().GetNanoseconds()),
+		)
+	}
+}
+
+// Removes inverse offers from outstanding, unknown ids are ignored
+func (m *Maintenance) take(ids []*mesos.OfferID) []*inverseOffer {
+	m.Lock()
+	defer m.Unlock()
+
+	var res []*inverseOffer
+	for _, id := range ids {
+		if o, ok := m.outstanding[id.GetValue()]; ok {
+			delete(m.outstanding, id.GetValue())
+			res = append(res, o)
+		}
+	}
+
+	return res
+}
+
+func (m *Maintenance) restore(offers []*inverseOffer) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, o := range offers {
+		m.outstanding[o.offer.GetId().GetValue()] = o
+	}
+}
+
+func (m *Maintenance) signal() {
+	select {
+	case m.check <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Maintenance) run(ctx context.Context) {
+	tick := time.NewTicker(m.drainInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-m.check:
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+
+		m.acceptDrained(ctx)
+	}
+}
+
+// Accepts outstanding inverse offers of agents reported as drained by policy
+func (m *Maintenance) acceptDrained(ctx context.Context) {
+	byAgent := make(map[string][]*mesos.OfferID)
+	for _, o := range m.InverseOffers() {
+		agentId := o.GetAgentId().GetValue()
+		byAgent[agentId] = append(byAgent[agentId], o.GetId())
+	}
+
+	agents := make([]string, 0, len(byAgent))
+	for agentId := range byAgent {
+		agents = append(agents, agentId)
+	}
+	sort.Strings(agents)
+
+	m.Lock()
+	fwId := m.frameworkId
+	m.Unlock()
+
+	for _, agentId := range agents {
+		if !m.drain.Drained(agentId) {
+			continue
+		}
+
+		call := scheduler.AcceptInverseOffers(byAgent[agentId]...).With(scheduler.FrameworkId(fwId))
+		if err := m.Push(call, ctx); err != nil {
+			m.log.Log("event", "drain_accept_failed", "agent", agentId, "err", err)
+		} else {
+			m.log.Log("event", "drained", "agent", agentId, "inverse_offers", len(byAgent[agentId]))
+		}
+	}
+}
+
+type byReceived []*inverseOffer
+
+func (b byReceived) Len() int      { return len(b) }
+func (b byReceived) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byReceived) Less(i, j int) bool {
+	if b[i].received.Equal(b[j].received) {
+		return b[i].seq < b[j].seq
+	}
+	return b[i].received.Before(b[j].received)
+}
+
+type byStart []Window
+
+func (b byStart) Len() int      { return len(b) }
+func (b byStart) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byStart) Less(i, j int) bool {
+	if b[i].Start.Equal(b[j].Start) {
+		return b[i].AgentId < b[j].AgentId
+	}
+	return b[i].Start.Before(b[j].Start)
+}
@@ -0,0 +1,263 @@
+package maintenance_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/maintenance"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+func TestMaintenance(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Maintenance stage suite")
+}
+
+var _ = Describe("Maintenance stage", func() {
+
+	start := time.Unix(1500000000, 0)
+
+	unavailability := func(d time.Duration) *mesos.Unavailability {
+		u := &mesos.Unavailability{Start: &mesos.TimeInfo{Nanoseconds: mesos.I64p(start.UnixNano())}}
+		if d > 0 {
+			u.Duration = &mesos.DurationInfo{Nanoseconds: mesos.I64p(int64(d))}
+		}
+		return u
+	}
+
+	offerId := func(id string) *mesos.OfferID {
+		return &mesos.OfferID{Value: mesos.Strp(id)}
+	}
+
+	inverse := func(id, agentId string) *mesos.InverseOffer {
+		return &mesos.InverseOffer{
+			Id:             offerId(id),
+			AgentId:        &mesos.AgentID{Value: mesos.Strp(agentId)},
+			Unavailability: unavailability(time.Hour),
+		}
+	}
+
+	inverseOffers := func(offers ...*mesos.InverseOffer) *scheduler.Event {
+		return &scheduler.Event{
+			Type:          scheduler.Event_INVERSE_OFFERS.Enum(),
+			InverseOffers: &scheduler.Event_InverseOffers{InverseOffers: offers},
+		}
+	}
+
+	rescind := func(id string) *scheduler.Event {
+		return &scheduler.Event{
+			Type:                scheduler.Event_RESCIND_INVERSE_OFFER.Enum(),
+			RescindInverseOffer: &scheduler.Event_RescindInverseOffer{InverseOfferId: offerId(id)},
+		}
+	}
+
+	update := func(taskId string) *scheduler.Event {
+		return &scheduler.Event{
+			Type: scheduler.Event_UPDATE.Enum(),
+			Update: &scheduler.Event_Update{
+				Status: &mesos.TaskStatus{
+					TaskId: &mesos.TaskID{Value: mesos.Strp(taskId)},
+					State:  mesos.TaskState_TASK_KILLED.Enum(),
+				},
+			},
+		}
+	}
+
+	It("Track inverse offers per agent", func(done Done) {
+		m := New()
+		sink := flow.NewTestFlow()
+		m.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(inverseOffers(inverse("1", "a1"), inverse("2", "a2"), inverse("3", "a1")))
+			sink.ExpectPull().Message(rescind("3"))
+			sink.ExpectPush().Error(errors.New("boom"))
+			sink.ExpectPush().OK()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+		}()
+
+		_, err := m.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(m.InverseOffers()).To(HaveLen(3))
+		Expect(m.AgentInverseOffers("a1")).To(Equal([]*mesos.InverseOffer{inverse("1", "a1"), inverse("3", "a1")}))
+
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(m.AgentInverseOffers("a1")).To(Equal([]*mesos.InverseOffer{inverse("1", "a1")}))
+
+		// kept when decline fails
+		Expect(m.Push(scheduler.DeclineInverseOffers(offerId("2")), ctx)).NotTo(Succeed())
+		Expect(m.AgentInverseOffers("a2")).To(HaveLen(1))
+
+		Expect(m.Push(scheduler.DeclineInverseOffers(offerId("2")), ctx)).To(Succeed())
+		Expect(m.AgentInverseOffers("a2")).To(BeEmpty())
+
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(m.InverseOffers()).To(BeEmpty())
+		Expect(m.Windows()).To(BeEmpty())
+
+		close(done)
+	})
+
+	It("Handle calls and events without body", func(done Done) {
+		m := New()
+		sink := flow.NewTestFlow()
+		m.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(inverseOffers(inverse("1", "a1")))
+			sink.ExpectPull().Message(&scheduler.Event{Type: scheduler.Event_RESCIND_INVERSE_OFFER.Enum()})
+			sink.ExpectPush().OK()
+			sink.ExpectPush().OK()
+		}()
+
+		_, err := m.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		Expect(m.Push(&scheduler.Call{Type: scheduler.Call_ACCEPT_INVERSE_OFFERS.Enum()}, ctx)).To(Succeed())
+		Expect(m.Push(&scheduler.Call{Type: scheduler.Call_DECLINE_INVERSE_OFFERS.Enum()}, ctx)).To(Succeed())
+		Expect(m.InverseOffers()).To(HaveLen(1))
+
+		close(done)
+	})
+
+	It("Expose unavailability of offered agents", func(done Done) {
+		m := New()
+		sink := flow.NewTestFlow()
+		m.Via(sink)
+		ctx := context.Background()
+
+		offers := func(agentId string, u *mesos.Unavailability) *scheduler.Event {
+			return &scheduler.Event{
+				Type: scheduler.Event_OFFERS.Enum(),
+				Offers: &scheduler.Event_Offers{Offers: []*mesos.Offer{{
+					Id:             offerId("o-" + agentId),
+					AgentId:        &mesos.AgentID{Value: mesos.Strp(agentId)},
+					Unavailability: u,
+				}}},
+			}
+		}
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(offers("a1", unavailability(time.Hour)))
+			sink.ExpectPull().Message(offers("a2", unavailability(0)))
+			sink.ExpectPull().Message(offers("a1", nil))
+		}()
+
+		_, err := m.Pull(ctx)
+		Expect(err).To(Succeed())
+		w, ok := m.Unavailability("a1")
+		Expect(ok).To(BeTrue())
+		Expect(w.Start.Equal(start)).To(BeTrue())
+		Expect(w.End().Equal(start.Add(time.Hour))).To(BeTrue())
+		Expect(w.Contains(start.Add(time.Minute))).To(BeTrue())
+		Expect(w.Contains(start.Add(time.Hour))).To(BeFalse())
+		Expect(w.Overlaps(start.Add(-time.Hour), 30*time.Minute)).To(BeFalse())
+		Expect(w.Overlaps(start.Add(-time.Hour), 2*time.Hour)).To(BeTrue())
+
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+		w, ok = m.Unavailability("a2")
+		Expect(ok).To(BeTrue())
+		Expect(w.IsBounded()).To(BeFalse())
+		Expect(w.Contains(start.Add(1000 * time.Hour))).To(BeTrue())
+		Expect(m.Windows()).To(HaveLen(2))
+
+		// maintenance of a1 was cancelled
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, ok = m.Unavailability("a1")
+		Expect(ok).To(BeFalse())
+		Expect(m.Windows()).To(HaveLen(1))
+
+		close(done)
+	})
+
+	It("Accept inverse offers of drained agents", func(done Done) {
+		var mu sync.Mutex
+		drained := map[string]bool{}
+		policy := DrainPolicyFunc(func(agentId string) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return drained[agentId]
+		})
+
+		m := New(WithDrainPolicy(policy), WithDrainInterval(time.Hour))
+		sink := flow.NewTestFlow()
+		m.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(inverseOffers(inverse("1", "a1"), inverse("2", "a2")))
+		}()
+
+		_, err := m.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		sink.ExpectNoPush(20 * time.Millisecond)
+
+		mu.Lock()
+		drained["a2"] = true
+		mu.Unlock()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(update("t1"))
+		}()
+
+		_, err = m.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		push := sink.ExpectPush()
+		c, ok := push.Msg.(*scheduler.Call)
+		Expect(ok).To(BeTrue())
+		Expect(c.GetType()).To(Equal(scheduler.Call_ACCEPT_INVERSE_OFFERS))
+		Expect(c.AcceptInverseOffers.InverseOfferIds).To(Equal([]*mesos.OfferID{offerId("2")}))
+		Expect(c.GetFrameworkId().GetValue()).To(Equal("1"))
+		push.OK()
+
+		Eventually(m.InverseOffers).Should(Equal([]*mesos.InverseOffer{inverse("1", "a1")}))
+
+		go func() {
+			defer GinkgoRecover()
+			sink.AcceptClose().OK()
+		}()
+		Expect(m.Close()).To(Succeed())
+
+		close(done)
+	})
+
+	It("Consider agent drained when store has no running tasks on it", func() {
+		store := scheduler.NewMemoryTaskStore()
+		Expect(store.Put(&scheduler.Task{TaskId: mesos.Strp("t1"), AgentId: mesos.Strp("a1")})).To(Succeed())
+
+		policy := StoreDrained(store)
+		Expect(policy.Drained("a1")).To(BeFalse())
+		Expect(policy.Drained("a2")).To(BeTrue())
+
+		status := update("t1").Update.Status
+		status.AgentId = &mesos.AgentID{Value: mesos.Strp("a1")}
+		Expect(store.Update(status)).To(Succeed())
+		Expect(policy.Drained("a1")).To(BeTrue())
+	})
+})
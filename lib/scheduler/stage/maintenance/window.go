@@ -0,0 +1,78 @@
+package maintenance
+
+import (
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+)
+
+// Period of time when agent is going to be unavailable
+type Window struct {
+	AgentId string
+	Start   time.Time
+	// 0 when agent is unavailable indefinitely
+	Duration time.Duration
+}
+
+// Returns window of agent, zero window when unavailability is nil
+func WindowOf(agentId string, u *mesos.Unavailability) Window {
+	if u == nil {
+		return Window{AgentId: agentId}
+	}
+
+	w := Window{
+		AgentId: agentId,
+		Start:   time.Unix(0, u.GetStart().GetNanoseconds()),
+	}
+	if u.Duration != nil {
+		w.Duration = time.Duration(u.GetDuration().GetNanoseconds())
+	}
+
+	return w
+}
+
+// Returns window of agent that made offer, false when offer carries no unavailability
+func OfferWindow(o *mesos.Offer) (Window, bool) {
+	if o.GetUnavailability() == nil {
+		return Window{}, false
+	}
+	return WindowOf(o.GetAgentId().GetValue(), o.GetUnavailability()), true
+}
+
+func (w Window) IsZero() bool {
+	return w.Start.IsZero()
+}
+
+// Returns false when agent is unavailable indefinitely
+func (w Window) IsBounded() bool {
+	return w.Duration > 0
+}
+
+// Returns end of window, zero time when window is not bounded
+func (w Window) End() time.Time {
+	if !w.IsBounded() {
+		return time.Time{}
+	}
+	return w.Start.Add(w.Duration)
+}
+
+// Returns true when agent is unavailable at time t
+func (w Window) Contains(t time.Time) bool {
+	if w.IsZero() || t.Before(w.Start) {
+		return false
+	}
+	return !w.IsBounded() || t.Before(w.End())
+}
+
+// Returns true when work started at start and running for d would be affected by window (d <= 0 means forever)
+func (w Window) Overlaps(start time.Time, d time.Duration) bool {
+	if w.IsZero() {
+		return false
+	}
+
+	if w.IsBounded() && !start.Before(w.End()) {
+		return false
+	}
+
+	return d <= 0 || start.Add(d).After(w.Start)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/ondrej-smola/mesos-go-http/lib"
 	"github.com/ondrej-smola/mesos-go-http/lib/flow"
 	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
 )
@@ -26,7 +27,8 @@ type (
 		OffersReceived(count uint32)
 		OffersDeclined(count uint32)
 
-		// called when pulled message that contains resource offers, only for scalar resources
+		// called when pulled message that contains resource offers, only for scalar resources.
+		// Role is allocation role of resource (multi-role frameworks) or its reservation role.
 		ResourceOffered(name string, role string, value float64)
 	}
 
@@ -79,7 +81,7 @@ func (m *metrics) Pull(ctx context.Context) (flow.Message, error) {
 				for _, res := range o.Resources {
 					// only scalar resources are supported
 					if res.Scalar != nil {
-						m.monit.ResourceOffered(res.GetName(), res.GetRole(), res.Scalar.GetValue())
+						m.monit.ResourceOffered(res.GetName(), resourceRole(o, res), res.Scalar.GetValue())
 					}
 				}
 			}
@@ -91,6 +93,16 @@ func (m *metrics) Pull(ctx context.Context) (flow.Message, error) {
 	return msg, err
 }
 
+func resourceRole(o *mesos.Offer, r *mesos.Resource) string {
+	if role := r.AllocationRole(); role != "" {
+		return role
+	}
+	if role := o.AllocationRole(); role != "" {
+		return role
+	}
+	return r.GetRole()
+}
+
 func (m *metrics) Via(f flow.Flow) {
 	m.via = f
 }
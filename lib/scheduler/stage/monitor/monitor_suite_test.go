@@ -79,6 +79,11 @@ var _ = Describe("Monitor", func() {
 				Offers: []*mesos.Offer{
 					{Resources: []*mesos.Resource{cpus, mem}},
 					{Resources: []*mesos.Resource{mem}},
+					{Resources: []*mesos.Resource{resources.Cpus(2).WithAllocationRole("a")}},
+					{
+						AllocationInfo: &mesos.Resource_AllocationInfo{Role: mesos.Strp("b")},
+						Resources:      []*mesos.Resource{resources.Cpus(3).WithRole("b")},
+					},
 				},
 			},
 		}
@@ -106,11 +111,13 @@ var _ = Describe("Monitor", func() {
 		testMonit.Lock()
 		Expect(testMonit.PullC[ping.Name()]).To(BeEquivalentTo(1))
 		Expect(testMonit.PullC[offers.Name()]).To(BeEquivalentTo(1))
-		Expect(testMonit.OffersC).To(BeEquivalentTo(2))
+		Expect(testMonit.OffersC).To(BeEquivalentTo(4))
 		Expect(testMonit.PullErrC).To(BeEquivalentTo(1))
 		Expect(testMonit.PullLatencyC).To(BeNumerically(">=", 15*time.Millisecond))
 		Expect(testMonit.ResourcesC[cpus.GetName()+":"+mesos.Default_Resource_Role]).To(BeEquivalentTo(1))
 		Expect(testMonit.ResourcesC[mem.GetName()+":"+mesos.Default_Resource_Role]).To(BeEquivalentTo(1024))
+		Expect(testMonit.ResourcesC[cpus.GetName()+":a"]).To(BeEquivalentTo(2))
+		Expect(testMonit.ResourcesC[cpus.GetName()+":b"]).To(BeEquivalentTo(3))
 		testMonit.Unlock()
 
 		close(done)
@@ -0,0 +1,313 @@
+package offers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+// Returned by Push when accepted offer is not outstanding (unknown, rescinded, declined or already accepted)
+var ErrUnknownOffer = errors.New("Offers: offer is unknown or no longer available")
+
+type (
+	Opt func(c *Offers)
+
+	offer struct {
+		offer    *mesos.Offer
+		received time.Time
+		// orders offers received at the same time
+		seq   uint64
+		timer *time.Timer
+	}
+
+	Offers struct {
+		holdTime           time.Duration
+		refuseSeconds      *time.Duration
+		validateAllocation bool
+
+		outstanding map[string]*offer
+		seq         uint64
+		// framework id from subscribed event, set on automatically declined offers
+		frameworkId string
+
+		via flow.Flow
+		log log.Logger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		sync.Mutex
+	}
+)
+
+const DEFAULT_HOLD_TIME = 30 * time.Second
+
+// How long are offers held before they are automatically declined, 0 disables automatic decline
+func WithHoldTime(d time.Duration) Opt {
+	if d < 0 {
+		panic(fmt.Sprintf("Hold time must be >= 0, is %v", d))
+	}
+
+	return func(c *Offers) {
+		c.holdTime = d
+	}
+}
+
+// Set refuse seconds filter on automatically declined offers
+func WithRefuseSeconds(d time.Duration) Opt {
+	return func(c *Offers) {
+		c.refuseSeconds = &d
+	}
+}
+
+// Reject accept launching tasks with resources not allocated to role of offer (multi-role frameworks)
+func WithAllocationValidation() Opt {
+	return func(c *Offers) {
+		c.validateAllocation = true
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Offers) {
+		c.log = l
+	}
+}
+
+func Blueprint(opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "offers_stage")))
+		}
+		return New(opts...)
+	})
+}
+
+// Tracks outstanding offers.
+// Offers are removed when rescinded, accepted or declined, offers not used within hold time are declined automatically
+// (with framework id from subscribed event).
+// Accept of offer that is not outstanding fails with ErrUnknownOffer without being sent to Mesos.
+// All outstanding offers are forgotten on (re)subscribe.
+func New(opts ...Opt) *Offers {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	o := &Offers{
+		holdTime:    DEFAULT_HOLD_TIME,
+		outstanding: make(map[string]*offer),
+		log:         log.NewNopLogger(),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+var _ = flow.Stage(&Offers{})
+
+// Returns outstanding offers ordered by time of receive
+func (o *Offers) Outstanding() []*mesos.Offer {
+	o.Lock()
+	all := make([]*offer, 0, len(o.outstanding))
+	for _, of := range o.outstanding {
+		all = append(all, of)
+	}
+	o.Unlock()
+
+	sort.Sort(byReceived(all))
+
+	res := make([]*mesos.Offer, len(all))
+	for i, of := range all {
+		res[i] = of.offer
+	}
+
+	return res
+}
+
+func (o *Offers) Push(ev flow.Message, ctx context.Context) error {
+	var used []*offer
+
+	switch c := ev.(type) {
+	case *scheduler.Call:
+		switch c.GetType() {
+		case scheduler.Call_ACCEPT:
+			ids := c.GetAccept().GetOfferIds()
+			var err error
+			if used, err = o.take(ids, true); err != nil {
+				return err
+			}
+			if o.validateAllocation {
+				for _, of := range used {
+					if err := scheduler.ValidateAllocation(of.offer, c.GetAccept().GetOperations()...); err != nil {
+						o.restore(used)
+						return err
+					}
+				}
+			}
+		case scheduler.Call_DECLINE:
+			// declining unknown offer is harmless
+			used, _ = o.take(c.GetDecline().GetOfferIds(), false)
+		}
+	}
+
+	err := o.via.Push(ev, ctx)
+	if err != nil && len(used) > 0 {
+		// offers are still valid in Mesos
+		o.restore(used)
+	}
+
+	return err
+}
+
+func (o *Offers) Pull(ctx context.Context) (flow.Message, error) {
+	ev, err := o.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := scheduler.EventOf(ev); ok {
+		switch e.GetType() {
+		case scheduler.Event_SUBSCRIBED:
+			o.Lock()
+			o.frameworkId = e.GetSubscribed().GetFrameworkId().GetValue()
+			o.Unlock()
+			o.reset()
+		case scheduler.Event_OFFERS:
+			o.add(e.GetOffers().GetOffers())
+		case scheduler.Event_RESCIND:
+			if taken, _ := o.take([]*mesos.OfferID{e.GetRescind().GetOfferId()}, false); len(taken) > 0 {
+				o.log.Log("event", "rescinded", "offer", e.GetRescind().GetOfferId().GetValue())
+			}
+		}
+	}
+
+	return ev, nil
+}
+
+func (o *Offers) Via(f flow.Flow) {
+	o.via = f
+}
+
+func (o *Offers) Close() error {
+	o.cancel()
+	o.reset()
+	return o.via.Close()
+}
+
+func (o *Offers) add(offers []*mesos.Offer) {
+	o.Lock()
+	defer o.Unlock()
+
+	now := time.Now()
+	for _, of := range offers {
+		id := of.Id.GetValue()
+		o.seq += 1
+		entry := &offer{offer: of, received: now, seq: o.seq}
+		if o.holdTime > 0 {
+			entry.timer = time.AfterFunc(o.holdTime, func() { o.expire(id, entry) })
+		}
+		o.outstanding[id] = entry
+	}
+}
+
+// Removes offers from outstanding, when all is true and any offer is not outstanding nothing is removed
+func (o *Offers) take(ids []*mesos.OfferID, all bool) ([]*offer, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if all {
+		for _, id := range ids {
+			if _, ok := o.outstanding[id.GetValue()]; !ok {
+				return nil, errors.Wrapf(ErrUnknownOffer, "offer %v", id.GetValue())
+			}
+		}
+	}
+
+	var res []*offer
+	for _, id := range ids {
+		if of, ok := o.outstanding[id.GetValue()]; ok {
+			if of.timer != nil {
+				of.timer.Stop()
+			}
+			delete(o.outstanding, id.GetValue())
+			res = append(res, of)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, errors.Wrap(ErrUnknownOffer, "no outstanding offer")
+	}
+
+	return res, nil
+}
+
+func (o *Offers) restore(offers []*offer) {
+	o.Lock()
+	defer o.Unlock()
+
+	for _, of := range offers {
+		id := of.offer.Id.GetValue()
+		entry := &offer{offer: of.offer, received: of.received, seq: of.seq}
+		if o.holdTime > 0 {
+			remaining := o.holdTime - time.Now().Sub(of.received)
+			entry.timer = time.AfterFunc(remaining, func() { o.expire(id, entry) })
+		}
+		o.outstanding[id] = entry
+	}
+}
+
+func (o *Offers) reset() {
+	o.Lock()
+	defer o.Unlock()
+
+	for id, of := range o.outstanding {
+		if of.timer != nil {
+			of.timer.Stop()
+		}
+		delete(o.outstanding, id)
+	}
+}
+
+func (o *Offers) expire(id string, entry *offer) {
+	o.Lock()
+	current, ok := o.outstanding[id]
+	if !ok || current != entry {
+		o.Unlock()
+		return
+	}
+	delete(o.outstanding, id)
+	fwId := o.frameworkId
+	o.Unlock()
+
+	decline := scheduler.Decline(entry.offer.Id).With(scheduler.FrameworkId(fwId))
+	if o.refuseSeconds != nil {
+		decline.With(scheduler.RefuseSeconds(*o.refuseSeconds))
+	}
+
+	if err := o.via.Push(decline, o.ctx); err != nil {
+		o.log.Log("event", "auto_decline_failed", "offer", id, "err", err)
+	} else {
+		o.log.Log("event", "auto_declined", "offer", id)
+	}
+}
+
+type byReceived []*offer
+
+func (b byReceived) Len() int      { return len(b) }
+func (b byReceived) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byReceived) Less(i, j int) bool {
+	if b[i].received.Equal(b[j].received) {
+		return b[i].seq < b[j].seq
+	}
+	return b[i].received.Before(b[j].received)
+}
@@ -0,0 +1,216 @@
+package offers_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/offers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/resources"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+func TestOffers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Offers stage suite")
+}
+
+var _ = Describe("Offers stage", func() {
+
+	offerId := func(id string) *mesos.OfferID {
+		return &mesos.OfferID{Value: mesos.Strp(id)}
+	}
+
+	offers := func(ids ...string) *scheduler.Event {
+		ev := &scheduler.Event{Type: scheduler.Event_OFFERS.Enum(), Offers: &scheduler.Event_Offers{}}
+		for _, id := range ids {
+			ev.Offers.Offers = append(ev.Offers.Offers, &mesos.Offer{Id: offerId(id)})
+		}
+		return ev
+	}
+
+	rescind := func(id string) *scheduler.Event {
+		return &scheduler.Event{
+			Type:    scheduler.Event_RESCIND.Enum(),
+			Rescind: &scheduler.Event_Rescind{OfferId: offerId(id)},
+		}
+	}
+
+	It("Track outstanding offers", func(done Done) {
+		o := New()
+		sink := flow.NewTestFlow()
+		o.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(offers("1", "2", "3"))
+			sink.ExpectPull().Message(rescind("2"))
+			sink.ExpectPush().OK()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+		}()
+
+		_, err := o.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(o.Outstanding()).To(HaveLen(3))
+
+		_, err = o.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(o.Outstanding()).To(Equal([]*mesos.Offer{{Id: offerId("1")}, {Id: offerId("3")}}))
+
+		Expect(o.Push(scheduler.Decline(offerId("1")), ctx)).To(Succeed())
+		Expect(o.Outstanding()).To(Equal([]*mesos.Offer{{Id: offerId("3")}}))
+
+		_, err = o.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(o.Outstanding()).To(BeEmpty())
+
+		close(done)
+	})
+
+	It("Reject accept of unknown or rescinded offer", func(done Done) {
+		o := New()
+		sink := flow.NewTestFlow()
+		o.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(offers("1", "2"))
+			sink.ExpectPull().Message(rescind("2"))
+			sink.ExpectPush().OK()
+			sink.ExpectNoPush(20 * time.Millisecond)
+		}()
+
+		_, err := o.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = o.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		err = o.Push(scheduler.AcceptOffer(offerId("2")), ctx)
+		Expect(errors.Cause(err)).To(Equal(ErrUnknownOffer))
+
+		Expect(o.Push(scheduler.AcceptOffer(offerId("1")), ctx)).To(Succeed())
+
+		// already accepted
+		err = o.Push(scheduler.AcceptOffer(offerId("1")), ctx)
+		Expect(errors.Cause(err)).To(Equal(ErrUnknownOffer))
+
+		close(done)
+	})
+
+	It("Handle calls and events without body", func(done Done) {
+		o := New()
+		sink := flow.NewTestFlow()
+		o.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(offers("1"))
+			sink.ExpectPull().Message(&scheduler.Event{Type: scheduler.Event_RESCIND.Enum()})
+			sink.ExpectPush().OK()
+		}()
+
+		_, err := o.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = o.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		err = o.Push(&scheduler.Call{Type: scheduler.Call_ACCEPT.Enum()}, ctx)
+		Expect(errors.Cause(err)).To(Equal(ErrUnknownOffer))
+		Expect(o.Push(&scheduler.Call{Type: scheduler.Call_DECLINE.Enum()}, ctx)).To(Succeed())
+		Expect(o.Outstanding()).To(HaveLen(1))
+
+		close(done)
+	})
+
+	It("Reject launch of resources not allocated to role of offer", func(done Done) {
+		o := New(WithAllocationValidation())
+		sink := flow.NewTestFlow()
+		o.Via(sink)
+		ctx := context.Background()
+
+		ev := offers("1")
+		ev.Offers.Offers[0].AllocationInfo = &mesos.Resource_AllocationInfo{Role: mesos.Strp("a")}
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(ev)
+			sink.ExpectPush().OK()
+		}()
+
+		_, err := o.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		launch := func(role string) *mesos.Offer_Operation {
+			return scheduler.OpLaunch(&mesos.TaskInfo{Resources: []*mesos.Resource{resources.Cpus(1).WithAllocationRole(role)}})
+		}
+
+		Expect(o.Push(scheduler.AcceptOffer(offerId("1"), launch("b")), ctx)).To(HaveOccurred())
+		Expect(o.Outstanding()).To(HaveLen(1))
+		Expect(o.Push(scheduler.AcceptOffer(offerId("1"), launch("a")), ctx)).To(Succeed())
+		Expect(o.Outstanding()).To(BeEmpty())
+
+		close(done)
+	})
+
+	It("Keep offer when accept failed", func(done Done) {
+		o := New()
+		sink := flow.NewTestFlow()
+		o.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(offers("1"))
+			sink.ExpectPush().Error(errors.New("boom"))
+		}()
+
+		_, err := o.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(o.Push(scheduler.AcceptOffer(offerId("1")), ctx)).To(HaveOccurred())
+		Expect(o.Outstanding()).To(HaveLen(1))
+
+		close(done)
+	})
+
+	It("Decline offers not used within hold time", func(done Done) {
+		o := New(WithHoldTime(10*time.Millisecond), WithRefuseSeconds(5*time.Second))
+		sink := flow.NewTestFlow()
+		o.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			sink.ExpectPull().Message(offers("1"))
+		}()
+
+		_, err := o.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = o.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		push := sink.ExpectPush()
+		c, ok := push.Msg.(*scheduler.Call)
+		Expect(ok).To(BeTrue())
+		Expect(c.GetType()).To(Equal(scheduler.Call_DECLINE))
+		Expect(c.FrameworkId.GetValue()).To(Equal("1"))
+		Expect(c.Decline.OfferIds).To(Equal([]*mesos.OfferID{offerId("1")}))
+		Expect(c.Decline.Filters.GetRefuseSeconds()).To(BeEquivalentTo(5))
+		push.OK()
+
+		Expect(o.Outstanding()).To(BeEmpty())
+		err = o.Push(scheduler.AcceptOffer(offerId("1")), ctx)
+		Expect(errors.Cause(err)).To(Equal(ErrUnknownOffer))
+
+		close(done)
+	})
+})
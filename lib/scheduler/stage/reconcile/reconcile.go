@@ -0,0 +1,300 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/backoff"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+type (
+	Opt func(c *Reconcile)
+
+	// Source of tasks to be reconciled
+	TaskSource interface {
+		// Returns task id -> agent id (may be empty) of all tasks that are not in terminal state
+		Tasks() map[string]string
+	}
+
+	TaskSourceFunc func() map[string]string
+
+	// Pulled when explicit reconciliation finished.
+	// When attempts are exhausted message is returned by pull following next message received from Mesos.
+	ReconciledMessage struct {
+		// Tasks (task id -> agent id) without status update when reconciliation attempts were exhausted
+		Unreconciled map[string]string
+	}
+
+	// Reconciliation started by single subscribed event
+	run struct {
+		// framework id from subscribed event, set on reconcile calls
+		frameworkId string
+		pending     map[string]string
+		done        chan struct{}
+		finished    bool
+		cancel      context.CancelFunc
+	}
+
+	Reconcile struct {
+		tasks            TaskSource
+		backoff          backoff.Provider
+		implicitInterval time.Duration
+
+		current *run
+		// messages to be returned by pull before pulling from next flow
+		queue []flow.Message
+
+		via flow.Flow
+		log log.Logger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		sync.Mutex
+	}
+)
+
+const DEFAULT_IMPLICIT_INTERVAL = 10 * time.Minute
+
+func (f TaskSourceFunc) Tasks() map[string]string {
+	return f()
+}
+
+// Reconciles non-terminal tasks of store
+func StoreSource(s scheduler.TaskStore) TaskSource {
+	return TaskSourceFunc(func() map[string]string {
+		return scheduler.NonTerminalTasks(s)
+	})
+}
+
+func (r *ReconciledMessage) Name() string {
+	return "reconciled"
+}
+
+var _ = flow.Message(&ReconciledMessage{})
+
+// Backoff for explicit reconciliation retries, attempts are finished when provider max attempts is reached
+func WithBackoff(p backoff.Provider) Opt {
+	return func(c *Reconcile) {
+		c.backoff = p
+	}
+}
+
+// Interval of implicit reconciliation after explicit reconciliation finished, 0 means implicit reconciliation is done once
+func WithImplicitInterval(d time.Duration) Opt {
+	if d < 0 {
+		panic(fmt.Sprintf("Implicit interval must be >= 0, is %v", d))
+	}
+
+	return func(c *Reconcile) {
+		c.implicitInterval = d
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Reconcile) {
+		c.log = l
+	}
+}
+
+func Blueprint(tasks TaskSource, opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "reconcile_stage")))
+		}
+		return New(tasks, opts...)
+	})
+}
+
+// After every subscribed event explicitly reconciles all known tasks until status update is received for all of them
+// (retries are done using backoff). When all tasks are reconciled (or attempts are exhausted) ReconciledMessage is
+// returned by following pull and implicit reconciliation is done periodically.
+// Reconcile calls are sent with framework id from subscribed event.
+func New(tasks TaskSource, opts ...Opt) *Reconcile {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Reconcile{
+		tasks: tasks,
+		backoff: backoff.New(
+			backoff.Always(),
+			backoff.WithMinWait(5*time.Second),
+			backoff.WithMaxWait(2*time.Minute),
+		),
+		implicitInterval: DEFAULT_IMPLICIT_INTERVAL,
+		log:              log.NewNopLogger(),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
+}
+
+var _ = flow.Stage(&Reconcile{})
+
+func (r *Reconcile) Push(ev flow.Message, ctx context.Context) error {
+	return r.via.Push(ev, ctx)
+}
+
+func (r *Reconcile) Pull(ctx context.Context) (flow.Message, error) {
+	r.Lock()
+	if len(r.queue) > 0 {
+		msg := r.queue[0]
+		r.queue = r.queue[1:]
+		r.Unlock()
+		return msg, nil
+	}
+	r.Unlock()
+
+	ev, err := r.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := scheduler.EventOf(ev); ok {
+		switch e.GetType() {
+		case scheduler.Event_SUBSCRIBED:
+			r.start(e.GetSubscribed().GetFrameworkId().GetValue())
+		case scheduler.Event_UPDATE:
+			r.update(e.GetUpdate().GetStatus().GetTaskId().GetValue())
+		}
+	}
+
+	return ev, nil
+}
+
+func (r *Reconcile) Via(f flow.Flow) {
+	r.via = f
+}
+
+func (r *Reconcile) Close() error {
+	r.cancel()
+	return r.via.Close()
+}
+
+func (r *Reconcile) start(frameworkId string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.current != nil {
+		r.current.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	run := &run{
+		frameworkId: frameworkId,
+		pending:     r.tasks.Tasks(),
+		done:        make(chan struct{}),
+		cancel:      cancel,
+	}
+	if run.pending == nil {
+		run.pending = make(map[string]string)
+	}
+	r.current = run
+
+	r.log.Log("event", "reconciliation_started", "tasks", len(run.pending))
+
+	if len(run.pending) == 0 {
+		r.finish(run, nil)
+	}
+
+	go r.reconcile(ctx, run)
+}
+
+func (r *Reconcile) update(taskId string) {
+	r.Lock()
+	defer r.Unlock()
+
+	run := r.current
+	if run == nil || run.finished {
+		return
+	}
+
+	delete(run.pending, taskId)
+	if len(run.pending) == 0 {
+		r.finish(run, nil)
+	}
+}
+
+// must be called with lock held
+func (r *Reconcile) finish(run *run, unreconciled map[string]string) {
+	if run.finished {
+		return
+	}
+
+	run.finished = true
+	close(run.done)
+	r.queue = append(r.queue, &ReconciledMessage{Unreconciled: unreconciled})
+	r.log.Log("event", "reconciliation_finished", "unreconciled", len(unreconciled))
+}
+
+func (r *Reconcile) remaining(run *run) map[string]string {
+	r.Lock()
+	defer r.Unlock()
+
+	res := make(map[string]string, len(run.pending))
+	for k, v := range run.pending {
+		res[k] = v
+	}
+	return res
+}
+
+func (r *Reconcile) reconcile(ctx context.Context, run *run) {
+	retry := r.backoff.New(ctx)
+	defer retry.Close()
+
+explicit:
+	for {
+		select {
+		case attempt, ok := <-retry.Attempts():
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+				r.Lock()
+				r.finish(run, run.pending)
+				r.Unlock()
+				break explicit
+			}
+
+			tasks := r.remaining(run)
+			if len(tasks) == 0 {
+				break explicit
+			}
+
+			r.log.Log("event", "explicit_reconciliation", "attempt", attempt, "tasks", len(tasks))
+			if err := r.via.Push(scheduler.ReconcileTasks(tasks).With(scheduler.FrameworkId(run.frameworkId)), ctx); err != nil {
+				r.log.Log("event", "explicit_reconciliation", "attempt", attempt, "err", err)
+			}
+		case <-run.done:
+			break explicit
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	for {
+		r.log.Log("event", "implicit_reconciliation")
+		if err := r.via.Push(scheduler.ReconcileTasks(nil).With(scheduler.FrameworkId(run.frameworkId)), ctx); err != nil {
+			r.log.Log("event", "implicit_reconciliation", "err", err)
+		}
+
+		if r.implicitInterval == 0 {
+			return
+		}
+
+		select {
+		case <-time.After(r.implicitInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package reconcile_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/reconcile"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/backoff"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+func TestReconcile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Reconcile stage suite")
+}
+
+var _ = Describe("Reconcile stage", func() {
+
+	update := func(taskId string) *scheduler.Event {
+		return &scheduler.Event{
+			Type: scheduler.Event_UPDATE.Enum(),
+			Update: &scheduler.Event_Update{
+				Status: &mesos.TaskStatus{
+					TaskId: &mesos.TaskID{Value: mesos.Strp(taskId)},
+					State:  mesos.TaskState_TASK_RUNNING.Enum(),
+					Reason: mesos.TaskStatus_REASON_RECONCILIATION.Enum(),
+				},
+			},
+		}
+	}
+
+	tasks := func(tasks map[string]string) TaskSource {
+		return TaskSourceFunc(func() map[string]string {
+			res := make(map[string]string)
+			for k, v := range tasks {
+				res[k] = v
+			}
+			return res
+		})
+	}
+
+	reconciled := func(m flow.Message) map[string]string {
+		c, ok := m.(*scheduler.Call)
+		Expect(ok).To(BeTrue())
+		Expect(c.GetType()).To(Equal(scheduler.Call_RECONCILE))
+
+		res := make(map[string]string)
+		for _, t := range c.Reconcile.Tasks {
+			res[t.TaskId.GetValue()] = t.AgentId.GetValue()
+		}
+		return res
+	}
+
+	retry := func(attempts int) backoff.Provider {
+		return backoff.New(
+			backoff.WithMaxAttempts(attempts),
+			backoff.WithMinWait(20*time.Millisecond),
+			backoff.WithMaxWait(20*time.Millisecond),
+			backoff.WithJitterFraction(0),
+		)
+	}
+
+	It("Reconcile tasks until all reported", func(done Done) {
+		r := New(
+			tasks(map[string]string{"1": "a1", "2": "a2"}),
+			WithBackoff(retry(100)),
+			WithImplicitInterval(0),
+		)
+		sink := flow.NewTestFlow()
+		r.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+
+			push := sink.ExpectPush()
+			Expect(reconciled(push.Msg)).To(Equal(map[string]string{"1": "a1", "2": "a2"}))
+			// framework id is set from subscribed event
+			Expect(push.Msg.(*scheduler.Call).GetFrameworkId().GetValue()).To(Equal("1"))
+			push.OK()
+
+			sink.ExpectPull().Message(update("1"))
+
+			push = sink.ExpectPush()
+			Expect(reconciled(push.Msg)).To(Equal(map[string]string{"2": "a2"}))
+			push.OK()
+
+			sink.ExpectPull().Message(update("2"))
+
+			// implicit reconciliation
+			push = sink.ExpectPush()
+			Expect(reconciled(push.Msg)).To(BeEmpty())
+			Expect(push.Msg.(*scheduler.Call).GetFrameworkId().GetValue()).To(Equal("1"))
+			push.OK()
+		}()
+
+		_, err := r.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = r.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = r.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		msg, err := r.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(&ReconciledMessage{}))
+
+		close(done)
+	})
+
+	It("Report unreconciled tasks when attempts are exhausted", func(done Done) {
+		r := New(
+			tasks(map[string]string{"1": "a1"}),
+			WithBackoff(retry(2)),
+			WithImplicitInterval(0),
+		)
+		sink := flow.NewTestFlow()
+		r.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			for i := 0; i < 2; i++ {
+				push := sink.ExpectPush()
+				Expect(reconciled(push.Msg)).To(Equal(map[string]string{"1": "a1"}))
+				push.OK()
+			}
+
+			push := sink.ExpectPush()
+			Expect(reconciled(push.Msg)).To(BeEmpty())
+			push.OK()
+
+			sink.ExpectPull().Message(scheduler.TestHeartbeat())
+		}()
+
+		_, err := r.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = r.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		msg, err := r.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(&ReconciledMessage{Unreconciled: map[string]string{"1": "a1"}}))
+
+		close(done)
+	})
+
+	It("Finish immediately when there are no tasks", func(done Done) {
+		r := New(tasks(nil), WithImplicitInterval(10*time.Millisecond))
+		sink := flow.NewTestFlow()
+		r.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+			for i := 0; i < 2; i++ {
+				push := sink.ExpectPush()
+				Expect(reconciled(push.Msg)).To(BeEmpty())
+				push.OK()
+			}
+		}()
+
+		_, err := r.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		msg, err := r.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(&ReconciledMessage{}))
+
+		close(done)
+	})
+})
@@ -0,0 +1,128 @@
+package record
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ondrej-smola/mesos-go-http/lib/codec"
+	"github.com/ondrej-smola/mesos-go-http/lib/codec/framing/recordio"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+type (
+	// Single scheduler event or call captured from flow.
+	// Hand written protobuf message, wire compatible with:
+	//
+	//	message Record {
+	//	  required int64 timestamp = 1; // unix nanoseconds
+	//	  optional mesos.v1.scheduler.Event event = 2;
+	//	  optional mesos.v1.scheduler.Call call = 3;
+	//	}
+	Record struct {
+		Timestamp *int64           `protobuf:"varint,1,req,name=timestamp" json:"timestamp,omitempty"`
+		Event     *scheduler.Event `protobuf:"bytes,2,opt,name=event" json:"event,omitempty"`
+		Call      *scheduler.Call  `protobuf:"bytes,3,opt,name=call" json:"call,omitempty"`
+	}
+
+	// Writes records as recordio framed protobuf messages, safe for concurrent use
+	Writer struct {
+		enc *codec.Encoder
+		now func() time.Time
+		sync.Mutex
+	}
+
+	// Reads records written by Writer
+	Reader struct {
+		dec *codec.Decoder
+	}
+)
+
+func (m *Record) Reset()         { *m = Record{} }
+func (m *Record) String() string { return proto.CompactTextString(m) }
+func (*Record) ProtoMessage()    {}
+
+func (m *Record) GetTimestamp() int64 {
+	if m != nil && m.Timestamp != nil {
+		return *m.Timestamp
+	}
+	return 0
+}
+
+func (m *Record) Time() time.Time {
+	return time.Unix(0, m.GetTimestamp())
+}
+
+// Returns recorded event or call (nil when record is empty)
+func (m *Record) Message() flow.Message {
+	if m.Event != nil {
+		return m.Event
+	} else if m.Call != nil {
+		return m.Call
+	}
+	return nil
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		enc: codec.NewProtobufEncoder(recordio.NewWriter(w)),
+		now: time.Now,
+	}
+}
+
+// Records scheduler event or call, other messages are ignored
+func (w *Writer) Write(m flow.Message) error {
+	rec := &Record{}
+
+	switch r := m.(type) {
+	case *scheduler.Event:
+		rec.Event = r
+	case scheduler.EventMessage:
+		if rec.Event = r.Event(); rec.Event == nil {
+			return nil
+		}
+	case *scheduler.Call:
+		rec.Call = r
+	default:
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	ts := w.now().UnixNano()
+	rec.Timestamp = &ts
+
+	return w.enc.Encode(rec)
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: codec.NewProtobufDecoder(recordio.New(r))}
+}
+
+// Returns io.EOF when there are no more records
+func (r *Reader) Read() (*Record, error) {
+	rec := &Record{}
+	if err := r.dec.Decode(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Reads all records until EOF
+func ReadAll(r io.Reader) ([]*Record, error) {
+	rr := NewReader(r)
+	res := []*Record{}
+
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			return res, nil
+		} else if err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+}
@@ -0,0 +1,159 @@
+package record_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/record"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+func TestRecord(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Record stage suite")
+}
+
+var _ = Describe("Record stage", func() {
+
+	It("Record pushed calls and pulled events", func(done Done) {
+		buf := &bytes.Buffer{}
+		rec := New(NewWriter(buf))
+		sink := flow.NewTestFlow()
+		rec.Via(sink)
+
+		subscribed := scheduler.TestSubscribed("1")
+		heartbeat := scheduler.TestHeartbeat()
+		teardown := scheduler.Teardown()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(subscribed)
+			sink.ExpectPush().OK()
+			sink.ExpectPull().Message(&scheduler.PingMessage{})
+			sink.ExpectPull().Message(heartbeat)
+		}()
+
+		ctx := context.Background()
+		_, err := rec.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(rec.Push(teardown, ctx)).To(Succeed())
+		_, err = rec.Pull(ctx)
+		Expect(err).To(Succeed())
+		_, err = rec.Pull(ctx)
+		Expect(err).To(Succeed())
+
+		records, err := ReadAll(buf)
+		Expect(err).To(Succeed())
+		Expect(records).To(HaveLen(3))
+		Expect(records[0].Message()).To(Equal(subscribed))
+		Expect(records[1].Message()).To(Equal(teardown))
+		Expect(records[2].Message()).To(Equal(heartbeat))
+		Expect(records[0].GetTimestamp()).To(BeNumerically(">", 0))
+		Expect(records[2].Time()).To(BeTemporally(">=", records[0].Time()))
+
+		close(done)
+	})
+})
+
+var _ = Describe("Replay sink", func() {
+
+	record := func(msgs ...flow.Message) []*Record {
+		buf := &bytes.Buffer{}
+		w := NewWriter(buf)
+		for _, m := range msgs {
+			Expect(w.Write(m)).To(Succeed())
+		}
+		records, err := ReadAll(buf)
+		Expect(err).To(Succeed())
+		return records
+	}
+
+	It("Replay recorded events and loop back other messages", func(done Done) {
+		records := record(scheduler.TestSubscribed("1"), scheduler.Teardown(), scheduler.TestHeartbeat())
+		replay := NewReplay(records)
+		ctx := context.Background()
+
+		msg, err := replay.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(scheduler.TestSubscribed("1")))
+
+		ping := &scheduler.PingMessage{}
+		Expect(replay.Push(ping, ctx)).To(Succeed())
+		Expect(replay.Push(scheduler.Decline(), ctx)).To(Succeed())
+
+		msg, err = replay.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(ping))
+
+		msg, err = replay.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(scheduler.TestHeartbeat()))
+
+		_, err = replay.Pull(ctx)
+		Expect(err).To(Equal(io.EOF))
+
+		close(done)
+	})
+
+	It("Keep event when pull is cancelled while waiting", func(done Done) {
+		records := record(scheduler.TestSubscribed("1"), scheduler.TestHeartbeat())
+		ts := time.Now().UnixNano()
+		records[0].Timestamp = &ts
+		next := ts + int64(50*time.Millisecond)
+		records[1].Timestamp = &next
+
+		replay := NewReplay(records, WithRecordedTiming(1))
+		ctx := context.Background()
+
+		msg, err := replay.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(scheduler.TestSubscribed("1")))
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = replay.Pull(timeout)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+
+		msg, err = replay.Pull(ctx)
+		Expect(err).To(Succeed())
+		Expect(msg).To(Equal(scheduler.TestHeartbeat()))
+
+		_, err = replay.Pull(ctx)
+		Expect(err).To(Equal(io.EOF))
+
+		close(done)
+	})
+
+	It("Fail on unexpected call in strict mode", func(done Done) {
+		records := record(scheduler.TestSubscribed("1"), scheduler.Teardown())
+		replay := NewReplay(records, WithStrictCalls())
+		ctx := context.Background()
+
+		Expect(replay.Push(scheduler.Teardown(), ctx)).To(Succeed())
+		Expect(replay.Push(scheduler.Teardown(), ctx)).To(HaveOccurred())
+
+		replay = NewReplay(records, WithStrictCalls())
+		Expect(replay.Push(scheduler.Revive(), ctx)).To(HaveOccurred())
+
+		close(done)
+	})
+
+	It("Return context cancelled after close", func(done Done) {
+		replay := NewReplay(record(scheduler.TestHeartbeat()))
+		ctx := context.Background()
+
+		Expect(replay.Close()).To(Succeed())
+		_, err := replay.Pull(ctx)
+		Expect(err).To(Equal(context.Canceled))
+		Expect(replay.Push(scheduler.Teardown(), ctx)).To(Equal(context.Canceled))
+
+		close(done)
+	})
+})
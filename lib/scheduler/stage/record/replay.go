@@ -0,0 +1,223 @@
+package record
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+type (
+	ReplayOpt func(c *Replay)
+
+	// Sink emulating Mesos master by replaying recorded events.
+	// Recorded events are returned by Pull in recorded order, non call messages are
+	// looped back (same as scheduler.Client) and io.EOF is returned when all events were replayed.
+	Replay struct {
+		events []*Record
+		calls  []*Record
+
+		strict     bool
+		speed      float64
+		bufferSize int
+
+		// timestamp of last replayed event
+		lastTs int64
+
+		buffer chan flow.Message
+
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		log log.Logger
+		sync.Mutex
+	}
+)
+
+func WithReplayLogger(l log.Logger) ReplayOpt {
+	return func(c *Replay) {
+		c.log = l
+	}
+}
+
+// Every pushed call must be equal to next recorded call, otherwise push fails
+func WithStrictCalls() ReplayOpt {
+	return func(c *Replay) {
+		c.strict = true
+	}
+}
+
+// Wait between replayed events as recorded, speed of 2 replays events twice as fast
+func WithRecordedTiming(speed float64) ReplayOpt {
+	if speed <= 0 {
+		panic(fmt.Sprintf("Speed must be > 0, is %v", speed))
+	}
+
+	return func(c *Replay) {
+		c.speed = speed
+	}
+}
+
+func WithReplayBufferSize(size int) ReplayOpt {
+	return func(c *Replay) {
+		c.bufferSize = size
+	}
+}
+
+// Every materialized sink replays all provided records from beginning
+func ReplayBlueprint(records []*Record, opts ...ReplayOpt) flow.SinkBlueprint {
+	return flow.SinkBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Sink {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithReplayLogger(log.With(cfg.Log, "src", "replay_sink")))
+		}
+		return NewReplay(records, opts...)
+	})
+}
+
+func NewReplay(records []*Record, opts ...ReplayOpt) *Replay {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Replay{
+		bufferSize: 16,
+		ctx:        ctx,
+		cancel:     cancel,
+		log:        log.NewNopLogger(),
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	for _, rec := range records {
+		if rec.Event != nil {
+			r.events = append(r.events, rec)
+		} else if rec.Call != nil {
+			r.calls = append(r.calls, rec)
+		}
+	}
+
+	r.buffer = make(chan flow.Message, r.bufferSize)
+
+	return r
+}
+
+var _ = flow.Sink(&Replay{})
+
+func (r *Replay) Push(ev flow.Message, ctx context.Context) error {
+	select {
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	default:
+	}
+
+	c, ok := ev.(*scheduler.Call)
+	if !ok {
+		select {
+		case r.buffer <- ev:
+			return nil
+		default:
+			return scheduler.ErrBufferFull
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.strict {
+		return nil
+	}
+
+	if len(r.calls) == 0 {
+		return errors.Errorf("Replay: unexpected call %v, no more calls recorded", c)
+	}
+
+	expected := r.calls[0].Call
+	r.calls = r.calls[1:]
+
+	if !proto.Equal(expected, c) {
+		return errors.Errorf("Replay: unexpected call %v, recorded %v", c, expected)
+	}
+
+	return nil
+}
+
+func (r *Replay) Pull(ctx context.Context) (flow.Message, error) {
+	// read looped back messages first
+	select {
+	case ev := <-r.buffer:
+		return ev, nil
+	default:
+	}
+
+	select {
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	default:
+	}
+
+	for {
+		r.Lock()
+		if len(r.events) == 0 {
+			r.Unlock()
+			return nil, io.EOF
+		}
+		// event is removed only after wait so it is not lost when pull is cancelled
+		next := r.events[0]
+
+		var wait time.Duration
+		if r.speed > 0 && r.lastTs > 0 {
+			wait = time.Duration(float64(next.GetTimestamp()-r.lastTs) / r.speed)
+		}
+		r.Unlock()
+
+		if wait > 0 {
+			if err := r.wait(wait, ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		r.Lock()
+		if len(r.events) == 0 || r.events[0] != next {
+			// taken by concurrent pull
+			r.Unlock()
+			continue
+		}
+		r.events = r.events[1:]
+		r.lastTs = next.GetTimestamp()
+		r.Unlock()
+
+		r.log.Log("event", "replayed", "type", next.Event.GetType().String(), "debug", true)
+		return next.Event, nil
+	}
+}
+
+func (r *Replay) wait(d time.Duration, ctx context.Context) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	}
+}
+
+// Pull/Push will return context.ContextCancelled after close
+func (r *Replay) Close() error {
+	r.cancel()
+	return nil
+}
+
+// implements flow.Sink interface
+func (r *Replay) IsSink() {}
@@ -0,0 +1,101 @@
+package record
+
+import (
+	"context"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/pkg/errors"
+)
+
+type (
+	Opt func(c *Recorder)
+
+	Recorder struct {
+		via           flow.Flow
+		w             *Writer
+		failOnFailure bool
+		log           log.Logger
+	}
+)
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Recorder) {
+		c.log = l
+	}
+}
+
+func WithFailOnWriteFailure() Opt {
+	return func(c *Recorder) {
+		c.failOnFailure = true
+	}
+}
+
+// All materialized flows share provided writer, so records of consecutive flows (e.g. after failover)
+// are appended to the same output
+func Blueprint(w *Writer, opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "record_stage")))
+		}
+		return New(w, opts...)
+	})
+}
+
+// Records all pushed calls and pulled events.
+// By default only logs failed writes and passes message through.
+func New(w *Writer, opts ...Opt) *Recorder {
+	r := &Recorder{
+		w:   w,
+		log: log.NewNopLogger(),
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
+}
+
+var _ = flow.Stage(&Recorder{})
+
+func (r *Recorder) Push(ev flow.Message, ctx context.Context) error {
+	if err := r.record(ev); err != nil {
+		return err
+	}
+
+	return r.via.Push(ev, ctx)
+}
+
+func (r *Recorder) Pull(ctx context.Context) (flow.Message, error) {
+	ev, err := r.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.record(ev); err != nil {
+		return nil, err
+	}
+
+	return ev, nil
+}
+
+func (r *Recorder) record(ev flow.Message) error {
+	if err := r.w.Write(ev); err != nil {
+		if r.failOnFailure {
+			return errors.Wrapf(err, "Failed to record %v", ev.Name())
+		}
+		r.log.Log("event", "record_failed", "msg", ev.Name(), "err", err)
+	}
+
+	return nil
+}
+
+func (r *Recorder) Via(f flow.Flow) {
+	r.via = f
+}
+
+func (r *Recorder) Close() error {
+	return r.via.Close()
+}
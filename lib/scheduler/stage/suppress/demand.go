@@ -0,0 +1,54 @@
+package suppress
+
+import (
+	"sync"
+)
+
+// Demand for offers by role, set by application and shared by all materialized stages.
+// Role "" stands for all roles of framework and should be used only by frameworks without MULTI_ROLE capability.
+type Demand struct {
+	roles map[string]bool
+	// closed and replaced on every change
+	changed chan struct{}
+	sync.Mutex
+}
+
+func NewDemand() *Demand {
+	return &Demand{
+		roles:   make(map[string]bool),
+		changed: make(chan struct{}),
+	}
+}
+
+// Sets whether there is pending work requiring offers for role
+func (d *Demand) Set(role string, pending bool) {
+	d.Lock()
+	defer d.Unlock()
+
+	if current, ok := d.roles[role]; ok && current == pending {
+		return
+	}
+
+	d.roles[role] = pending
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// Returns demand of all roles set so far
+func (d *Demand) Get() map[string]bool {
+	d.Lock()
+	defer d.Unlock()
+
+	res := make(map[string]bool, len(d.roles))
+	for k, v := range d.roles {
+		res[k] = v
+	}
+	return res
+}
+
+// Returns channel closed on next change
+func (d *Demand) Changed() <-chan struct{} {
+	d.Lock()
+	defer d.Unlock()
+	return d.changed
+}
@@ -0,0 +1,232 @@
+package suppress
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+type (
+	Opt func(c *Suppress)
+
+	Suppress struct {
+		demand   *Demand
+		debounce time.Duration
+
+		// suppressed state of roles as known by Mesos
+		suppressed map[string]bool
+		// framework id from subscribed event, set on sent calls
+		frameworkId string
+		cancelRun   context.CancelFunc
+
+		via flow.Flow
+		log log.Logger
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		sync.Mutex
+	}
+)
+
+const DEFAULT_DEBOUNCE = 1 * time.Second
+
+// Demand changes are applied after no change was made for given duration
+func WithDebounce(d time.Duration) Opt {
+	if d < 0 {
+		panic(fmt.Sprintf("Debounce must be >= 0, is %v", d))
+	}
+
+	return func(c *Suppress) {
+		c.debounce = d
+	}
+}
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Suppress) {
+		c.log = l
+	}
+}
+
+// All materialized flows share provided demand
+func Blueprint(demand *Demand, opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "suppress_stage")))
+		}
+		return New(demand, opts...)
+	})
+}
+
+// Suppresses offers for roles without demand and revives them when demand arrives.
+// As Mesos revives all roles on subscribe, suppressed state is re-applied after every subscribed event.
+// Calls that failed to be sent are retried after debounce interval.
+func New(demand *Demand, opts ...Opt) *Suppress {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Suppress{
+		demand:     demand,
+		debounce:   DEFAULT_DEBOUNCE,
+		suppressed: make(map[string]bool),
+		log:        log.NewNopLogger(),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+var _ = flow.Stage(&Suppress{})
+
+// Returns roles currently suppressed by this stage
+func (s *Suppress) Suppressed() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	res := []string{}
+	for role, suppressed := range s.suppressed {
+		if suppressed {
+			res = append(res, role)
+		}
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func (s *Suppress) Push(ev flow.Message, ctx context.Context) error {
+	return s.via.Push(ev, ctx)
+}
+
+func (s *Suppress) Pull(ctx context.Context) (flow.Message, error) {
+	ev, err := s.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if is, e := scheduler.IsSubscribedMessage(ev); is {
+		s.subscribed(e.GetSubscribed().GetFrameworkId().GetValue())
+	}
+
+	return ev, nil
+}
+
+func (s *Suppress) Via(f flow.Flow) {
+	s.via = f
+}
+
+func (s *Suppress) Close() error {
+	s.cancel()
+	return s.via.Close()
+}
+
+func (s *Suppress) subscribed(frameworkId string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.frameworkId = frameworkId
+
+	if s.cancelRun != nil {
+		s.cancelRun()
+	}
+
+	s.suppressed = make(map[string]bool)
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancelRun = cancel
+
+	go s.run(ctx)
+}
+
+func (s *Suppress) run(ctx context.Context) {
+	var retry <-chan time.Time
+
+	changed := s.demand.Changed()
+	if !s.sync(ctx) {
+		retry = time.After(s.debounce)
+	}
+
+	for {
+		select {
+		case <-changed:
+			changed = s.demand.Changed()
+			retry = time.After(s.debounce)
+		case <-retry:
+			retry = nil
+			if !s.sync(ctx) {
+				retry = time.After(s.debounce)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sends suppress and revive calls for roles whose demand differs from their state, returns false on failure
+func (s *Suppress) sync(ctx context.Context) bool {
+	var suppress, revive []string
+
+	s.Lock()
+	for role, pending := range s.demand.Get() {
+		if pending && s.suppressed[role] {
+			revive = append(revive, role)
+		} else if !pending && !s.suppressed[role] {
+			suppress = append(suppress, role)
+		}
+	}
+	s.Unlock()
+
+	ok := true
+	if len(revive) > 0 {
+		ok = s.send(ctx, scheduler.Revive, revive, false) && ok
+	}
+	if len(suppress) > 0 {
+		ok = s.send(ctx, scheduler.Suppress, suppress, true) && ok
+	}
+
+	return ok
+}
+
+func (s *Suppress) send(ctx context.Context, call func(roles ...string) *scheduler.Call, roles []string, suppressed bool) bool {
+	sort.Strings(roles)
+
+	callRoles := roles
+	if len(roles) == 1 && roles[0] == "" {
+		// all roles
+		callRoles = nil
+	}
+
+	s.Lock()
+	c := call(callRoles...).With(scheduler.FrameworkId(s.frameworkId))
+	s.Unlock()
+
+	if err := s.via.Push(c, ctx); err != nil {
+		s.log.Log("event", "push_failed", "type", c.GetType().String(), "roles", fmt.Sprint(roles), "err", err)
+		return false
+	}
+
+	s.log.Log("event", c.GetType().String(), "roles", fmt.Sprint(roles))
+
+	s.Lock()
+	defer s.Unlock()
+
+	if ctx.Err() != nil {
+		// resubscribed or closed meanwhile
+		return false
+	}
+
+	for _, r := range roles {
+		s.suppressed[r] = suppressed
+	}
+
+	return true
+}
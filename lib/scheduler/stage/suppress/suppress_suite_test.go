@@ -0,0 +1,94 @@
+package suppress_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/suppress"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+func TestSuppress(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Suppress stage suite")
+}
+
+var _ = Describe("Suppress stage", func() {
+
+	// calls are sent with framework id from subscribed event
+	expectCall := func(sink *flow.TestFlow, expected *scheduler.Call) {
+		push := sink.ExpectPush()
+		Expect(push.Msg).To(Equal(expected.With(scheduler.FrameworkId("1"))))
+		push.OK()
+	}
+
+	It("Suppress and revive roles based on demand", func(done Done) {
+		demand := NewDemand()
+		demand.Set("a", false)
+		demand.Set("b", true)
+
+		s := New(demand, WithDebounce(10*time.Millisecond))
+		sink := flow.NewTestFlow()
+		s.Via(sink)
+		ctx := context.Background()
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+		}()
+
+		_, err := s.Pull(ctx)
+		Expect(err).To(Succeed())
+		expectCall(sink, scheduler.Suppress("a"))
+		Eventually(s.Suppressed).Should(Equal([]string{"a"}))
+
+		demand.Set("a", true)
+		demand.Set("b", false)
+		expectCall(sink, scheduler.Revive("a"))
+		expectCall(sink, scheduler.Suppress("b"))
+		Eventually(s.Suppressed).Should(Equal([]string{"b"}))
+
+		// Mesos revives all roles on subscribe
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+		}()
+
+		_, err = s.Pull(ctx)
+		Expect(err).To(Succeed())
+		expectCall(sink, scheduler.Suppress("b"))
+
+		close(done)
+	})
+
+	It("Debounce demand changes", func(done Done) {
+		demand := NewDemand()
+		demand.Set("", true)
+
+		s := New(demand, WithDebounce(20*time.Millisecond))
+		sink := flow.NewTestFlow()
+		s.Via(sink)
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPull().Message(scheduler.TestSubscribed("1"))
+		}()
+
+		_, err := s.Pull(context.Background())
+		Expect(err).To(Succeed())
+
+		demand.Set("", false)
+		demand.Set("", true)
+		sink.ExpectNoPush(50 * time.Millisecond)
+
+		demand.Set("", false)
+		expectCall(sink, scheduler.Suppress())
+
+		close(done)
+	})
+})
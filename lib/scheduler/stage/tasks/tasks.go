@@ -0,0 +1,142 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/log"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+	"github.com/pkg/errors"
+)
+
+type (
+	Opt func(c *Tracker)
+
+	Tracker struct {
+		store         scheduler.TaskStore
+		failOnFailure bool
+
+		via flow.Flow
+		log log.Logger
+	}
+)
+
+func WithLogger(l log.Logger) Opt {
+	return func(c *Tracker) {
+		c.log = l
+	}
+}
+
+// Push/Pull fails when store cannot be updated
+func WithFailOnStoreFailure() Opt {
+	return func(c *Tracker) {
+		c.failOnFailure = true
+	}
+}
+
+// All materialized flows share provided store
+func Blueprint(store scheduler.TaskStore, opts ...Opt) flow.StageBlueprint {
+	return flow.StageBlueprintFunc(func(matOpts ...flow.MatOpt) flow.Stage {
+		cfg := flow.MatOpts(matOpts).Config()
+		if cfg.Log != nil {
+			opts = append(opts, WithLogger(log.With(cfg.Log, "src", "tasks_stage")))
+		}
+		return New(store, opts...)
+	})
+}
+
+// Keeps task store up to date.
+// Tasks launched by pushed accept calls are stored with desired state running (before call is sent),
+// pushed kill calls change desired state to killed and every pulled status update is stored as last known status.
+// By default store failures are only logged.
+func New(store scheduler.TaskStore, opts ...Opt) *Tracker {
+	t := &Tracker{
+		store: store,
+		log:   log.NewNopLogger(),
+	}
+
+	for _, o := range opts {
+		o(t)
+	}
+
+	return t
+}
+
+var _ = flow.Stage(&Tracker{})
+
+func (t *Tracker) Push(ev flow.Message, ctx context.Context) error {
+	if c, ok := ev.(*scheduler.Call); ok {
+		if err := t.handle(t.call(c)); err != nil {
+			return err
+		}
+	}
+
+	return t.via.Push(ev, ctx)
+}
+
+func (t *Tracker) Pull(ctx context.Context) (flow.Message, error) {
+	ev, err := t.via.Pull(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e, ok := scheduler.EventOf(ev); ok && scheduler.IsUpdate(e) {
+		if err := t.handle(t.store.Update(e.GetUpdate().GetStatus())); err != nil {
+			return nil, err
+		}
+	}
+
+	return ev, nil
+}
+
+func (t *Tracker) Via(f flow.Flow) {
+	t.via = f
+}
+
+func (t *Tracker) Close() error {
+	return t.via.Close()
+}
+
+func (t *Tracker) call(c *scheduler.Call) error {
+	switch c.GetType() {
+	case scheduler.Call_ACCEPT:
+		for _, op := range c.GetAccept().GetOperations() {
+			var launched []*mesos.TaskInfo
+
+			switch op.GetType() {
+			case mesos.Offer_Operation_LAUNCH:
+				launched = op.GetLaunch().GetTaskInfos()
+			case mesos.Offer_Operation_LAUNCH_GROUP:
+				launched = op.GetLaunchGroup().GetTaskGroup().GetTasks()
+			}
+
+			for _, ti := range launched {
+				err := t.store.Put(&scheduler.Task{
+					TaskId:  mesos.Strp(ti.GetTaskId().GetValue()),
+					AgentId: mesos.Strp(ti.GetAgentId().GetValue()),
+					Desired: scheduler.DESIRED_RUNNING.Enum(),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case scheduler.Call_KILL:
+		return t.store.SetDesired(c.GetKill().GetTaskId().GetValue(), scheduler.DESIRED_KILLED)
+	}
+
+	return nil
+}
+
+func (t *Tracker) handle(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	t.log.Log("event", "store_failed", "err", err)
+	if t.failOnFailure {
+		return errors.Wrap(err, "Tasks stage")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package tasks_test
+
+import (
+	. "github.com/ondrej-smola/mesos-go-http/lib/scheduler/stage/tasks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"context"
+	"testing"
+
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/flow"
+	"github.com/ondrej-smola/mesos-go-http/lib/scheduler"
+)
+
+func TestTasks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tasks stage suite")
+}
+
+var _ = Describe("Tasks stage", func() {
+
+	It("Track launched, killed and updated tasks", func(done Done) {
+		store := scheduler.NewMemoryTaskStore()
+		t := New(store)
+		sink := flow.NewTestFlow()
+		t.Via(sink)
+		ctx := context.Background()
+
+		task := &mesos.TaskInfo{
+			Name:    mesos.Strp("task"),
+			TaskId:  &mesos.TaskID{Value: mesos.Strp("1")},
+			AgentId: &mesos.AgentID{Value: mesos.Strp("a1")},
+		}
+
+		launch := scheduler.AcceptOffer(&mesos.OfferID{Value: mesos.Strp("o1")}, scheduler.OpLaunch(task))
+
+		update := &scheduler.Event{
+			Type: scheduler.Event_UPDATE.Enum(),
+			Update: &scheduler.Event_Update{
+				Status: &mesos.TaskStatus{
+					TaskId: task.TaskId,
+					State:  mesos.TaskState_TASK_RUNNING.Enum(),
+				},
+			},
+		}
+
+		go func() {
+			defer GinkgoRecover()
+			sink.ExpectPush().OK()
+			sink.ExpectPull().Message(update)
+			sink.ExpectPush().OK()
+		}()
+
+		Expect(t.Push(launch, ctx)).To(Succeed())
+		stored, ok := store.Get("1")
+		Expect(ok).To(BeTrue())
+		Expect(stored.GetAgentId()).To(Equal("a1"))
+		Expect(stored.GetDesired()).To(Equal(scheduler.DESIRED_RUNNING))
+		Expect(stored.GetStatus()).To(BeNil())
+
+		_, err := t.Pull(ctx)
+		Expect(err).To(Succeed())
+		stored, _ = store.Get("1")
+		Expect(stored.GetStatus().GetState()).To(Equal(mesos.TaskState_TASK_RUNNING))
+
+		Expect(t.Push(scheduler.Kill("1", "a1"), ctx)).To(Succeed())
+		stored, _ = store.Get("1")
+		Expect(stored.GetDesired()).To(Equal(scheduler.DESIRED_KILLED))
+
+		close(done)
+	})
+})
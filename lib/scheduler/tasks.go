@@ -0,0 +1,462 @@
+package scheduler
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ondrej-smola/mesos-go-http/lib"
+	"github.com/ondrej-smola/mesos-go-http/lib/codec"
+	"github.com/ondrej-smola/mesos-go-http/lib/codec/framing/recordio"
+	"github.com/pkg/errors"
+)
+
+type (
+	// State of task requested by framework
+	DesiredState int32
+
+	// Task known to framework.
+	// Hand written protobuf message, wire compatible with:
+	//
+	//	message Task {
+	//	  required string task_id = 1;
+	//	  optional string agent_id = 2;
+	//	  optional mesos.v1.TaskStatus status = 3; // last received status
+	//	  optional int32 desired = 4;
+	//	}
+	Task struct {
+		TaskId  *string           `protobuf:"bytes,1,req,name=task_id" json:"task_id,omitempty"`
+		AgentId *string           `protobuf:"bytes,2,opt,name=agent_id" json:"agent_id,omitempty"`
+		Status  *mesos.TaskStatus `protobuf:"bytes,3,opt,name=status" json:"status,omitempty"`
+		Desired *DesiredState     `protobuf:"varint,4,opt,name=desired" json:"desired,omitempty"`
+	}
+
+	// Stores state of tasks, implementations must be safe for concurrent use.
+	// Returned tasks are copies, modifying them does not change stored state.
+	TaskStore interface {
+		// Adds or replaces task
+		Put(t *Task) error
+		// Updates last known status of task, unknown task is added
+		Update(status *mesos.TaskStatus) error
+		// Sets desired state of known task (unknown task is ignored)
+		SetDesired(taskId string, d DesiredState) error
+		Remove(taskId string) error
+		Get(taskId string) (*Task, bool)
+		// All tasks ordered by task id
+		All() []*Task
+		// Tasks without status or with status in non-terminal state ordered by task id
+		NonTerminal() []*Task
+	}
+
+	// Keeps tasks in memory only
+	MemoryTaskStore struct {
+		tasks map[string]*Task
+		sync.RWMutex
+	}
+
+	// Keeps tasks in memory and persists every change to append only log of protobuf records.
+	// Log is replayed when store is opened, use Compact to rewrite log to contain only current tasks.
+	FileTaskStore struct {
+		path string
+		file *os.File
+		enc  *codec.Encoder
+		mem  *MemoryTaskStore
+		sync.Mutex
+	}
+
+	// Single change of task store.
+	// Hand written protobuf message, wire compatible with:
+	//
+	//	message TaskStoreEntry {
+	//	  optional Task task = 1; // added or replaced task
+	//	  optional string removed = 2; // id of removed task
+	//	}
+	taskStoreEntry struct {
+		Task    *Task   `protobuf:"bytes,1,opt,name=task" json:"task,omitempty"`
+		Removed *string `protobuf:"bytes,2,opt,name=removed" json:"removed,omitempty"`
+	}
+)
+
+const (
+	DESIRED_UNKNOWN DesiredState = iota
+	DESIRED_RUNNING
+	DESIRED_KILLED
+)
+
+func (d DesiredState) String() string {
+	switch d {
+	case DESIRED_RUNNING:
+		return "running"
+	case DESIRED_KILLED:
+		return "killed"
+	default:
+		return "unknown"
+	}
+}
+
+func (d DesiredState) Enum() *DesiredState {
+	return &d
+}
+
+func (m *Task) Reset()         { *m = Task{} }
+func (m *Task) String() string { return proto.CompactTextString(m) }
+func (*Task) ProtoMessage()    {}
+
+func (m *Task) GetTaskId() string {
+	if m != nil && m.TaskId != nil {
+		return *m.TaskId
+	}
+	return ""
+}
+
+func (m *Task) GetAgentId() string {
+	if m != nil && m.AgentId != nil {
+		return *m.AgentId
+	}
+	return ""
+}
+
+func (m *Task) GetStatus() *mesos.TaskStatus {
+	if m != nil {
+		return m.Status
+	}
+	return nil
+}
+
+func (m *Task) GetDesired() DesiredState {
+	if m != nil && m.Desired != nil {
+		return *m.Desired
+	}
+	return DESIRED_UNKNOWN
+}
+
+// Returns true when last known status of task is in terminal state
+func (m *Task) IsTerminal() bool {
+	return m.GetStatus() != nil && mesos.IsTerminalState(m.GetStatus().GetState())
+}
+
+func (m *taskStoreEntry) Reset()         { *m = taskStoreEntry{} }
+func (m *taskStoreEntry) String() string { return proto.CompactTextString(m) }
+func (*taskStoreEntry) ProtoMessage()    {}
+
+// Returns task id -> agent id of all non-terminal tasks (suitable for ReconcileTasks)
+func NonTerminalTasks(s TaskStore) map[string]string {
+	res := make(map[string]string)
+	for _, t := range s.NonTerminal() {
+		res[t.GetTaskId()] = t.GetAgentId()
+	}
+	return res
+}
+
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{tasks: make(map[string]*Task)}
+}
+
+var _ = TaskStore(&MemoryTaskStore{})
+
+func (m *MemoryTaskStore) Put(t *Task) error {
+	if t.GetTaskId() == "" {
+		return errors.New("TaskStore: task id must not be empty")
+	}
+
+	m.Lock()
+	m.tasks[t.GetTaskId()] = cloneTask(t)
+	m.Unlock()
+	return nil
+}
+
+func (m *MemoryTaskStore) Update(status *mesos.TaskStatus) error {
+	m.Lock()
+	defer m.Unlock()
+
+	t, err := m.updated(status)
+	if err != nil {
+		return err
+	}
+
+	m.tasks[t.GetTaskId()] = t
+	return nil
+}
+
+func (m *MemoryTaskStore) SetDesired(taskId string, d DesiredState) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if t, ok := m.withDesired(taskId, d); ok {
+		m.tasks[taskId] = t
+	}
+	return nil
+}
+
+func (m *MemoryTaskStore) Remove(taskId string) error {
+	m.Lock()
+	delete(m.tasks, taskId)
+	m.Unlock()
+	return nil
+}
+
+func (m *MemoryTaskStore) Get(taskId string) (*Task, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	t, ok := m.tasks[taskId]
+	if !ok {
+		return nil, false
+	}
+	return cloneTask(t), true
+}
+
+func (m *MemoryTaskStore) All() []*Task {
+	return m.filter(func(*Task) bool { return true })
+}
+
+func (m *MemoryTaskStore) NonTerminal() []*Task {
+	return m.filter(func(t *Task) bool { return !t.IsTerminal() })
+}
+
+func (m *MemoryTaskStore) filter(f func(t *Task) bool) []*Task {
+	m.RLock()
+	res := []*Task{}
+	for _, t := range m.tasks {
+		if f(t) {
+			res = append(res, cloneTask(t))
+		}
+	}
+	m.RUnlock()
+
+	sort.Sort(byTaskId(res))
+	return res
+}
+
+// Returns task with applied status (stored task is not modified), must be called with lock held
+func (m *MemoryTaskStore) updated(status *mesos.TaskStatus) (*Task, error) {
+	id := status.GetTaskId().GetValue()
+	if id == "" {
+		return nil, errors.New("TaskStore: status without task id")
+	}
+
+	t, ok := m.tasks[id]
+	if ok {
+		t = cloneTask(t)
+	} else {
+		t = &Task{TaskId: mesos.Strp(id)}
+	}
+
+	t.Status = proto.Clone(status).(*mesos.TaskStatus)
+	if aid := status.GetAgentId().GetValue(); aid != "" {
+		t.AgentId = mesos.Strp(aid)
+	}
+
+	return t, nil
+}
+
+// Returns task with desired state set (stored task is not modified), must be called with lock held
+func (m *MemoryTaskStore) withDesired(taskId string, d DesiredState) (*Task, bool) {
+	t, ok := m.tasks[taskId]
+	if !ok {
+		return nil, false
+	}
+
+	t = cloneTask(t)
+	t.Desired = d.Enum()
+	return t, true
+}
+
+// Opens (or creates) store backed by file at path, all changes found in file are replayed.
+// Incomplete last record (e.g. write interrupted by crash) is truncated.
+func NewFileTaskStore(path string) (*FileTaskStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "TaskStore: open")
+	}
+
+	mem := NewMemoryTaskStore()
+	cr := &countingReader{r: f}
+	br := bufio.NewReader(cr)
+	dec := codec.NewProtobufDecoder(recordio.New(br))
+	// end of last complete record
+	var offset int64
+	for {
+		e := &taskStoreEntry{}
+		if err := dec.Decode(e); err == io.EOF || err == io.ErrUnexpectedEOF {
+			// whole file was read, anything after last complete record is torn record
+			if cr.n > offset {
+				if err := f.Truncate(offset); err != nil {
+					f.Close()
+					return nil, errors.Wrapf(err, "TaskStore: truncate incomplete record of %v", path)
+				}
+			}
+			break
+		} else if err != nil {
+			f.Close()
+			return nil, errors.Wrapf(err, "TaskStore: read %v", path)
+		}
+		offset = cr.n - int64(br.Buffered())
+
+		if e.Task != nil {
+			mem.tasks[e.Task.GetTaskId()] = e.Task
+		}
+		if e.Removed != nil {
+			delete(mem.tasks, *e.Removed)
+		}
+	}
+
+	return &FileTaskStore{
+		path: path,
+		file: f,
+		enc:  codec.NewProtobufEncoder(recordio.NewWriter(f)),
+		mem:  mem,
+	}, nil
+}
+
+var _ = TaskStore(&FileTaskStore{})
+
+func (s *FileTaskStore) Put(t *Task) error {
+	if t.GetTaskId() == "" {
+		return errors.New("TaskStore: task id must not be empty")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	return s.apply(cloneTask(t))
+}
+
+func (s *FileTaskStore) Update(status *mesos.TaskStatus) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.mem.RLock()
+	t, err := s.mem.updated(status)
+	s.mem.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return s.apply(t)
+}
+
+func (s *FileTaskStore) SetDesired(taskId string, d DesiredState) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.mem.RLock()
+	t, ok := s.mem.withDesired(taskId, d)
+	s.mem.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	return s.apply(t)
+}
+
+func (s *FileTaskStore) Remove(taskId string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.mem.Get(taskId); !ok {
+		return nil
+	}
+
+	if err := s.enc.Encode(&taskStoreEntry{Removed: mesos.Strp(taskId)}); err != nil {
+		return errors.Wrap(err, "TaskStore: write")
+	}
+
+	return s.mem.Remove(taskId)
+}
+
+func (s *FileTaskStore) Get(taskId string) (*Task, bool) {
+	return s.mem.Get(taskId)
+}
+
+func (s *FileTaskStore) All() []*Task {
+	return s.mem.All()
+}
+
+func (s *FileTaskStore) NonTerminal() []*Task {
+	return s.mem.NonTerminal()
+}
+
+// Rewrites log to contain only current tasks
+func (s *FileTaskStore) Compact() error {
+	s.Lock()
+	defer s.Unlock()
+
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "TaskStore: compact")
+	}
+
+	enc := codec.NewProtobufEncoder(recordio.NewWriter(tmp))
+	for _, t := range s.mem.All() {
+		if err := enc.Encode(&taskStoreEntry{Task: t}); err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return errors.Wrap(err, "TaskStore: compact")
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "TaskStore: compact")
+	}
+
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "TaskStore: compact")
+	}
+
+	s.file.Close()
+	s.file = tmp
+	s.enc = enc
+
+	return nil
+}
+
+func (s *FileTaskStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.file.Close()
+}
+
+// must be called with lock held
+func (s *FileTaskStore) apply(t *Task) error {
+	if err := s.enc.Encode(&taskStoreEntry{Task: t}); err != nil {
+		return errors.Wrap(err, "TaskStore: write")
+	}
+
+	s.mem.Lock()
+	s.mem.tasks[t.GetTaskId()] = t
+	s.mem.Unlock()
+
+	return nil
+}
+
+func cloneTask(t *Task) *Task {
+	return proto.Clone(t).(*Task)
+}
+
+type byTaskId []*Task
+
+func (b byTaskId) Len() int           { return len(b) }
+func (b byTaskId) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byTaskId) Less(i, j int) bool { return b[i].GetTaskId() < b[j].GetTaskId() }
+
+// Counts bytes read from underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -17,7 +17,9 @@ func TestSubscribed(fwId string) *Event {
 		Subscribed: &Event_Subscribed{
 			FrameworkId: &mesos.FrameworkID{Value: &fwId},
 			MasterInfo: &mesos.MasterInfo{
-				Id: mesos.Strp("test_masters"),
+				Id:   mesos.Strp("test_masters"),
+				Ip:   mesos.UI32p(0x0100007f), // 127.0.0.1 in network order
+				Port: mesos.UI32p(5050),
 				Address: &mesos.Address{
 					Ip:   mesos.Strp("127.0.0.1"),
 					Port: mesos.I32p(5050),
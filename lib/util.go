@@ -67,3 +67,20 @@ func BoolP(b bool) *bool {
 func Strp(s string) *string {
 	return &s
 }
+
+// Returns true when task in given state will not receive further status updates
+func IsTerminalState(s TaskState) bool {
+	switch s {
+	case TaskState_TASK_FINISHED,
+		TaskState_TASK_FAILED,
+		TaskState_TASK_KILLED,
+		TaskState_TASK_ERROR,
+		TaskState_TASK_LOST,
+		TaskState_TASK_DROPPED,
+		TaskState_TASK_GONE,
+		TaskState_TASK_GONE_BY_OPERATOR:
+		return true
+	default:
+		return false
+	}
+}